@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"configure/internal/config"
+)
+
+// HandleCommitComment applies the candidate configuration and stores the
+// given comment with the archived commit
+func (cm *CommandManager) HandleCommitComment(fields []string) error {
+	comment := strings.Join(fields, " ")
+	if comment == "" {
+		return fmt.Errorf("missing commit comment")
+	}
+	_, err := cm.commit(comment)
+	return err
+}
+
+// HandleCompareRevisions shows the difference between two archived revisions,
+// or between an archived revision and the candidate configuration
+func (cm *CommandManager) HandleCompareRevisions(fields []string) error {
+	var revs []int
+	for _, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return fmt.Errorf("invalid revision number: %s", f)
+		}
+		revs = append(revs, n)
+	}
+
+	oldRev, err := cm.configManager.Revision(revs[0])
+	if err != nil {
+		return err
+	}
+	oldName := fmt.Sprintf("revision %d", revs[0])
+	newName := "candidate"
+	newCfg := cm.configManager.GetConfig()
+	if len(revs) == 2 {
+		newRev, err := cm.configManager.Revision(revs[1])
+		if err != nil {
+			return err
+		}
+		newName = fmt.Sprintf("revision %d", revs[1])
+		newCfg = newRev.Config
+	}
+
+	diff, err := config.UnifiedDiff(oldName, newName, oldRev.Config, newCfg)
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		fmt.Printf("No changes between %s and %s\n", oldName, newName)
+		return nil
+	}
+	fmt.Print(diff)
+	return nil
+}
+
+// HandleRollback loads an archived revision into the candidate configuration
+func (cm *CommandManager) HandleRollback(arg string) error {
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return fmt.Errorf("invalid revision number: %s", arg)
+	}
+	rev, err := cm.configManager.Revision(n)
+	if err != nil {
+		return err
+	}
+
+	cm.configManager.LoadCandidate(rev.Config)
+	fmt.Printf("Loaded revision %d into the candidate configuration; use commit to apply it\n", n)
+	return nil
+}
+
+// HandleSetCommitRevisions sets the number of commits kept in the commit archive
+func (cm *CommandManager) HandleSetCommitRevisions(arg string) error {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 || n > config.MaxCommitRevisions {
+		return fmt.Errorf("invalid commit-revisions: %s (expected 1-%d)", arg, config.MaxCommitRevisions)
+	}
+	cm.configManager.SetCommitRevisions(n)
+	fmt.Printf("Set system commit-revisions to %d\n", n)
+	return nil
+}
+
+// HandleDeleteCommitRevisions restores the default number of commits kept in the commit archive
+func (cm *CommandManager) HandleDeleteCommitRevisions() error {
+	if cm.configManager.GetConfig().System.CommitRevisions == 0 {
+		return fmt.Errorf("system commit-revisions is not configured")
+	}
+	cm.configManager.SetCommitRevisions(0)
+	fmt.Println("Deleted system commit-revisions")
+	return nil
+}
+
+// handleShowSystemCommit lists the archived commits, newest first
+func (cm *CommandManager) handleShowSystemCommit() error {
+	revs, err := cm.configManager.Revisions()
+	if err != nil {
+		return err
+	}
+	if len(revs) == 0 {
+		fmt.Println("No commits in the archive")
+		return nil
+	}
+	for n, rev := range revs {
+		fmt.Printf("%-4d %s by %s\n", n, rev.Time.Format("2006-01-02 15:04:05"), rev.User)
+		if rev.Comment != "" {
+			fmt.Printf("     %s\n", rev.Comment)
+		}
+	}
+	return nil
+}
+
+// archive stores the running configuration in the commit archive.
+// The commit itself already succeeded, so failures are only reported.
+func (cm *CommandManager) archive(comment string) {
+	if _, err := cm.configManager.Archive(currentUser(), comment); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to archive commit: %v\n", err)
+	}
+}
+
+// currentUser returns the name of the user running the CLI, looking through sudo
+func currentUser() string {
+	if name := os.Getenv("SUDO_USER"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "unknown"
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"configure/internal/lexer"
+)
+
+// RunBatch runs the commands read from r, one per line. Empty lines and
+// comments starting with # are skipped. It stops at the first failing command
+// unless keepGoing is set, and returns an error if any command failed.
+func (cm *CommandManager) RunBatch(r io.Reader, keepGoing bool) error {
+	scanner := bufio.NewScanner(r)
+	failed := 0
+	for lineNo := 1; scanner.Scan() && !cm.exited; lineNo++ {
+		fields, err := lexer.Split(scanner.Text())
+		if err == nil && len(fields) == 0 {
+			continue
+		}
+		if err == nil {
+			err = cm.HandleCommand(fields)
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "Error: line %d: %v\n", lineNo, err)
+			if !keepGoing {
+				return fmt.Errorf("stopped at line %d", lineNo)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read commands: %w", err)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d command(s) failed", failed)
+	}
+	return nil
+}
+
+// runBatchInput runs the commands of a file, or of stdin if file is empty or "-"
+func (cm *CommandManager) runBatchInput(file string, keepGoing bool) error {
+	if file == "" || file == "-" {
+		return cm.RunBatch(os.Stdin, keepGoing)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open command file: %w", err)
+	}
+	defer f.Close()
+	return cm.RunBatch(f, keepGoing)
+}
@@ -0,0 +1,414 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"configure/internal/config"
+	"configure/internal/schema"
+	"configure/internal/validator"
+)
+
+// Value types of the command schema
+
+var (
+	hostAddressValue = &schema.ValueType{
+		Name:     "<address>",
+		Validate: validator.ValidateHostAddress,
+		Formats: []schema.Format{
+			{Hint: "<x.x.x.x>", Help: "IPv4 address"},
+			{Hint: "<h:h:h:h:h:h:h:h>", Help: "IPv6 address"},
+		},
+	}
+	gatewayValue = &schema.ValueType{
+		Name:     "<gateway>",
+		Validate: validator.ValidateGateway,
+		Formats: []schema.Format{
+			{Hint: "<x.x.x.x>", Help: "IPv4 gateway address"},
+			{Hint: "<h:h:h:h:h:h:h:h>", Help: "IPv6 gateway address"},
+			{Hint: "<fe80::h%iface>", Help: "IPv6 link-local gateway on an interface"},
+		},
+	}
+	prefixAddressValue = &schema.ValueType{
+		Name:     "<address>",
+		Validate: validator.ValidateAddressWithPrefix,
+		Formats: []schema.Format{
+			{Hint: "<x.x.x.x/x>", Help: "IPv4 address and prefix"},
+			{Hint: "<h:h:h:h:h:h:h:h/x>", Help: "IPv6 address and prefix"},
+		},
+	}
+	routePrefixValue = &schema.ValueType{
+		Name:     "<prefix>",
+		Validate: validator.ValidatePrefix,
+		Formats: []schema.Format{
+			{Hint: "<x.x.x.x/x>", Help: "IPv4 destination prefix"},
+			{Hint: "<h:h:h:h:h:h:h:h/x>", Help: "IPv6 destination prefix"},
+		},
+	}
+	distanceValue = &schema.ValueType{
+		Name:     "<distance>",
+		Validate: validator.ValidateDistance,
+		Formats:  []schema.Format{{Hint: fmt.Sprintf("<%d-%d>", validator.MinDistance, validator.MaxDistance), Help: "Administrative distance, lower is preferred"}},
+	}
+	macValue = &schema.ValueType{
+		Name:     "<mac>",
+		Validate: validator.ValidateMACAddress,
+		Formats:  []schema.Format{{Hint: "<h:h:h:h:h:h>", Help: "MAC address"}},
+	}
+	descriptionValue = &schema.ValueType{
+		Name:     "<text>",
+		Validate: validator.ValidateDescription,
+		Formats:  []schema.Format{{Hint: "<text>", Help: "Interface description"}},
+	}
+	mtuValue = &schema.ValueType{
+		Name:     "<mtu>",
+		Validate: validator.ValidateMTU,
+		Formats:  []schema.Format{{Hint: fmt.Sprintf("<%d-%d>", validator.MinMTU, validator.MaxMTU), Help: "Maximum transmission unit"}},
+	}
+	speedValue = &schema.ValueType{
+		Name:     "<speed>",
+		Validate: validator.ValidateSpeed,
+		Complete: func() []string { return validator.LinkSpeeds },
+		Formats: []schema.Format{
+			{Hint: "auto", Help: "Autonegotiate speed"},
+			{Hint: "<Mbit/s>", Help: "Fixed speed, e.g. 1000"},
+		},
+	}
+	revisionValue = &schema.ValueType{
+		Name:     "<n>",
+		Validate: validateNumber,
+		Formats:  []schema.Format{{Hint: "<n>", Help: "Archived commit, 0 is the most recent"}},
+	}
+	otherRevision = &schema.ValueType{
+		Name:     "<m>",
+		Validate: validateNumber,
+		Formats:  []schema.Format{{Hint: "<m>", Help: "Archived commit to compare against"}},
+	}
+	minutesValue = &schema.ValueType{
+		Name:     "<minutes>",
+		Validate: validateNumber,
+		Formats:  []schema.Format{{Hint: "<minutes>", Help: "Minutes until automatic rollback"}},
+	}
+	countValue = &schema.ValueType{
+		Name:     "<n>",
+		Validate: validateNumber,
+		Formats:  []schema.Format{{Hint: "<1-65535>", Help: "Number of archived commits"}},
+	}
+	hostnameValue = &schema.ValueType{
+		Name:     "<hostname>",
+		Validate: validator.ValidateHostname,
+		Formats:  []schema.Format{{Hint: "<hostname>", Help: "Host name"}},
+	}
+	fileValue = &schema.ValueType{
+		Name:    "<file>",
+		Formats: []schema.Format{{Hint: "<file>", Help: "Path of a file"}},
+	}
+	textValue = &schema.ValueType{
+		Name:    "<text>",
+		Formats: []schema.Format{{Hint: "<text>", Help: "Free-form text"}},
+	}
+)
+
+// validateNumber checks that a value is a non-negative decimal number
+func validateNumber(s string) error {
+	if n, err := strconv.Atoi(s); err != nil || n < 0 {
+		return fmt.Errorf("%s is not a number", s)
+	}
+	return nil
+}
+
+// duplexHelp and offloadHelp describe the duplex and offload keywords
+var (
+	duplexHelp = map[string]string{
+		"auto": "Autonegotiate duplex",
+		"half": "Half duplex",
+		"full": "Full duplex",
+	}
+	offloadHelp = map[string]string{
+		"gro": "Generic receive offload",
+		"gso": "Generic segmentation offload",
+		"lro": "Large receive offload",
+		"sg":  "Scatter-gather",
+		"tso": "TCP segmentation offload",
+	}
+)
+
+// keywords returns one node per word, each running f with the interface
+// name and the word
+func keywords(words []string, help map[string]string, f func(iface, word string) error) []*schema.Node {
+	nodes := make([]*schema.Node, 0, len(words))
+	for _, word := range words {
+		word := word
+		nodes = append(nodes, &schema.Node{Keyword: word, Help: help[word], Run: func(args []string) error {
+			return f(args[0], word)
+		}})
+	}
+	return nodes
+}
+
+// noArgs adapts a handler without arguments to a schema handler
+func noArgs(f func() error) schema.Handler {
+	return func([]string) error { return f() }
+}
+
+// noError adapts a handler that cannot fail to a schema handler
+func noError(f func()) schema.Handler {
+	return func([]string) error {
+		f()
+		return nil
+	}
+}
+
+// commandTree returns the command schema bound to the handlers of the CommandManager.
+// Parsing, tab completion, ? help and the help command are all generated from it.
+func (cm *CommandManager) commandTree() *schema.Node {
+	interfaceValue := &schema.ValueType{
+		Name:     "<iface>",
+		Complete: cm.interfaceNames,
+		Formats:  []schema.Format{{Hint: "<iface>", Help: "Interface name, e.g. eth0"}},
+	}
+
+	edit := &schema.Node{Keyword: "edit", Help: "Enter a configuration level"}
+	root := &schema.Node{Children: []*schema.Node{
+		{Keyword: "set", Help: "Set a configuration value", Children: []*schema.Node{
+			{Keyword: "hostname", Help: "Host name", Children: []*schema.Node{
+				{Value: hostnameValue, Help: "Set host name", Run: func(args []string) error {
+					return cm.HandleSetHostname(args[0])
+				}},
+			}},
+			{Keyword: "dns", Help: "DNS servers", Children: []*schema.Node{
+				{Value: hostAddressValue, Help: "Set DNS address", Run: func(args []string) error {
+					return cm.HandleSetDNS(args[0])
+				}},
+			}},
+			{Keyword: "interfaces", Help: "Network interfaces", Children: []*schema.Node{
+				{Value: interfaceValue, Help: "Interface name", Children: []*schema.Node{
+					{Keyword: "address", Help: "Interface IP address", Children: []*schema.Node{
+						{Keyword: config.AddressDHCP, Help: "Obtain an IPv4 address with DHCP", Run: func(args []string) error {
+							return cm.HandleSetInterface([]string{args[0], "address", config.AddressDHCP})
+						}},
+						{Keyword: config.AddressDHCPv6, Help: "Obtain an IPv6 address with DHCPv6", Run: func(args []string) error {
+							return cm.HandleSetInterface([]string{args[0], "address", config.AddressDHCPv6})
+						}},
+						{Value: prefixAddressValue, Help: "Add interface IP address", Run: func(args []string) error {
+							return cm.HandleSetInterface([]string{args[0], "address", args[1]})
+						}},
+					}},
+					{Keyword: "ipv6", Help: "Interface IPv6 settings", Children: []*schema.Node{
+						{Keyword: "address", Help: "IPv6 addressing", Children: []*schema.Node{
+							{Keyword: "autoconf", Help: "Configure IPv6 addresses from router advertisements", Run: func(args []string) error {
+								return cm.HandleSetInterface([]string{args[0], "ipv6", "autoconf"})
+							}},
+						}},
+					}},
+					{Keyword: "mac", Help: "Interface MAC address", Children: []*schema.Node{
+						{Value: macValue, Help: "Set interface MAC address", Run: func(args []string) error {
+							return cm.HandleSetInterface([]string{args[0], "mac", args[1]})
+						}},
+					}},
+					{Keyword: "virtual", Help: "Declare an interface that need not exist yet", Run: func(args []string) error {
+						return cm.HandleSetInterface([]string{args[0], "virtual"})
+					}},
+					{Keyword: "description", Help: "Interface description", Children: []*schema.Node{
+						{Value: descriptionValue, Help: "Set interface description", Run: func(args []string) error {
+							return cm.HandleSetInterface([]string{args[0], "description", args[1]})
+						}},
+					}},
+					{Keyword: "mtu", Help: "Interface MTU", Children: []*schema.Node{
+						{Value: mtuValue, Help: "Set interface MTU", Run: func(args []string) error {
+							return cm.HandleSetInterface([]string{args[0], "mtu", args[1]})
+						}},
+					}},
+					{Keyword: "disable", Help: "Set the interface administratively down", Run: func(args []string) error {
+						return cm.HandleSetInterface([]string{args[0], "disable"})
+					}},
+					{Keyword: "speed", Help: "Ethernet link speed", Children: []*schema.Node{
+						{Value: speedValue, Help: "Set link speed", Run: func(args []string) error {
+							return cm.HandleSetInterface([]string{args[0], "speed", args[1]})
+						}},
+					}},
+					{Keyword: "duplex", Help: "Ethernet duplex mode", Children: keywords(validator.Duplexes, duplexHelp, func(iface, duplex string) error {
+						return cm.HandleSetInterface([]string{iface, "duplex", duplex})
+					})},
+					{Keyword: "offload", Help: "Enable an offload feature", Children: keywords(validator.OffloadFeatures, offloadHelp, func(iface, feature string) error {
+						return cm.HandleSetInterface([]string{iface, "offload", feature})
+					})},
+				}},
+			}},
+			{Keyword: "ip", Help: "IP settings", Children: []*schema.Node{
+				{Keyword: "route", Help: "Static routes", Children: []*schema.Node{
+					{Keyword: "default", Help: "Default route", Children: []*schema.Node{
+						{Keyword: "via", Help: "Gateway of the default route", Children: []*schema.Node{
+							{Value: gatewayValue, Help: "Set default route", Run: cm.HandleSetDefaultRoute},
+						}},
+					}},
+				}},
+			}},
+			{Keyword: "protocols", Help: "Routing protocols", Children: []*schema.Node{
+				{Keyword: "static", Help: "Static routing", Children: []*schema.Node{
+					{Keyword: "route", Help: "Static route", Children: []*schema.Node{
+						{Value: routePrefixValue, Help: "Destination prefix", Children: routeTargetNodes(interfaceValue, cm.HandleSetStaticRoute, false)},
+					}},
+				}},
+			}},
+			{Keyword: "system", Help: "System settings", Children: []*schema.Node{
+				{Keyword: "commit-revisions", Help: "Commit archive size", Children: []*schema.Node{
+					{Value: countValue, Help: "Set the number of archived commits to keep", Run: func(args []string) error {
+						return cm.HandleSetCommitRevisions(args[0])
+					}},
+				}},
+			}},
+		}},
+		{Keyword: "add", Help: "Add a value to a list", Children: []*schema.Node{
+			{Keyword: "dns", Help: "DNS servers", Children: []*schema.Node{
+				{Value: hostAddressValue, Help: "Add DNS address", Run: func(args []string) error {
+					return cm.HandleAddDNS(args[0])
+				}},
+			}},
+		}},
+		{Keyword: "delete", Help: "Delete a configuration value", Children: []*schema.Node{
+			{Keyword: "hostname", Help: "Delete host name", Run: noArgs(cm.HandleDeleteHostname)},
+			{Keyword: "dns", Help: "Delete all DNS addresses", Run: cm.HandleDeleteDNS, Children: []*schema.Node{
+				{Value: hostAddressValue, Help: "Delete DNS address", Run: cm.HandleDeleteDNS},
+			}},
+			{Keyword: "interfaces", Help: "Network interfaces", Children: []*schema.Node{
+				{Value: interfaceValue, Help: "Delete interface", Run: cm.HandleDeleteInterface, Children: []*schema.Node{
+					{Keyword: "address", Help: "Delete all interface IP addresses", Run: func(args []string) error {
+						return cm.HandleDeleteInterface([]string{args[0], "address"})
+					}, Children: []*schema.Node{
+						{Keyword: config.AddressDHCP, Help: "Stop DHCP", Run: func(args []string) error {
+							return cm.HandleDeleteInterface([]string{args[0], "address", config.AddressDHCP})
+						}},
+						{Keyword: config.AddressDHCPv6, Help: "Stop DHCPv6", Run: func(args []string) error {
+							return cm.HandleDeleteInterface([]string{args[0], "address", config.AddressDHCPv6})
+						}},
+						{Value: prefixAddressValue, Help: "Delete interface IP address", Run: func(args []string) error {
+							return cm.HandleDeleteInterface([]string{args[0], "address", args[1]})
+						}},
+					}},
+					{Keyword: "ipv6", Help: "Interface IPv6 settings", Children: []*schema.Node{
+						{Keyword: "address", Help: "IPv6 addressing", Children: []*schema.Node{
+							{Keyword: "autoconf", Help: "Disable IPv6 address autoconfiguration", Run: func(args []string) error {
+								return cm.HandleDeleteInterface([]string{args[0], "ipv6", "autoconf"})
+							}},
+						}},
+					}},
+					{Keyword: "mac", Help: "Delete interface MAC address", Run: func(args []string) error {
+						return cm.HandleDeleteInterface([]string{args[0], "mac"})
+					}},
+					{Keyword: "virtual", Help: "Require the interface to exist", Run: func(args []string) error {
+						return cm.HandleDeleteInterface([]string{args[0], "virtual"})
+					}},
+					{Keyword: "description", Help: "Delete interface description", Run: func(args []string) error {
+						return cm.HandleDeleteInterface([]string{args[0], "description"})
+					}},
+					{Keyword: "mtu", Help: "Restore the default MTU", Run: func(args []string) error {
+						return cm.HandleDeleteInterface([]string{args[0], "mtu"})
+					}},
+					{Keyword: "disable", Help: "Set the interface administratively up", Run: func(args []string) error {
+						return cm.HandleDeleteInterface([]string{args[0], "disable"})
+					}},
+					{Keyword: "speed", Help: "Autonegotiate link speed", Run: func(args []string) error {
+						return cm.HandleDeleteInterface([]string{args[0], "speed"})
+					}},
+					{Keyword: "duplex", Help: "Autonegotiate duplex mode", Run: func(args []string) error {
+						return cm.HandleDeleteInterface([]string{args[0], "duplex"})
+					}},
+					{Keyword: "offload", Help: "Disable all offload features", Run: func(args []string) error {
+						return cm.HandleDeleteInterface([]string{args[0], "offload"})
+					}, Children: keywords(validator.OffloadFeatures, offloadHelp, func(iface, feature string) error {
+						return cm.HandleDeleteInterface([]string{iface, "offload", feature})
+					})},
+				}},
+			}},
+			{Keyword: "ip", Help: "IP settings", Children: []*schema.Node{
+				{Keyword: "route", Help: "Static routes", Children: []*schema.Node{
+					{Keyword: "default", Help: "Delete default route", Run: noArgs(cm.HandleDeleteDefaultRoute)},
+				}},
+			}},
+			{Keyword: "protocols", Help: "Routing protocols", Children: []*schema.Node{
+				{Keyword: "static", Help: "Static routing", Children: []*schema.Node{
+					{Keyword: "route", Help: "Static route", Children: []*schema.Node{
+						{Value: routePrefixValue, Help: "Delete static route", Run: cm.HandleDeleteStaticRoute, Children: routeTargetNodes(interfaceValue, cm.HandleDeleteStaticRoute, true)},
+					}},
+				}},
+			}},
+			{Keyword: "system", Help: "System settings", Children: []*schema.Node{
+				{Keyword: "commit-revisions", Help: "Keep the default number of archived commits", Run: noArgs(cm.HandleDeleteCommitRevisions)},
+			}},
+		}},
+		edit,
+		{Keyword: "up", Help: "Leave the current configuration level", Run: noError(cm.handleUp)},
+		{Keyword: "top", Help: "Return to the top configuration level", Run: noError(cm.handleTop)},
+		{Keyword: "show", Help: "Show configuration and status", Children: []*schema.Node{
+			{Keyword: "dns", Help: "Show current DNS settings", Run: noError(cm.handleShowDNS)},
+			{Keyword: "config", Help: "Show current configuration", Run: noError(cm.handleShowConfig)},
+			{Keyword: "configuration", Help: "Show current configuration", Run: noError(cm.handleShowConfig), Children: []*schema.Node{
+				{Keyword: "commands", Help: "Show current configuration as set commands", Run: noError(cm.handleShowConfigurationCommands)},
+			}},
+			{Keyword: "interfaces", Help: "Show interface status", Run: noError(cm.handleShowInterfaces)},
+			{Keyword: "ip", Help: "IP status", Children: []*schema.Node{
+				{Keyword: "route", Help: "Show configured routes of the running configuration", Run: noError(cm.handleShowConfiguredRoutes)},
+			}},
+			{Keyword: "version", Help: "Show version information", Run: noError(cm.handleShowVersion)},
+			{Keyword: "system", Help: "System information", Children: []*schema.Node{
+				{Keyword: "commit", Help: "Show the commit archive", Run: noArgs(cm.handleShowSystemCommit)},
+			}},
+			{Keyword: "|", Help: "Pipe the configuration", Children: []*schema.Node{
+				{Keyword: "compare", Help: "Show uncommitted changes as a diff", Run: func([]string) error {
+					return cm.HandleCompare(false)
+				}},
+			}},
+		}},
+		{Keyword: "commit", Help: "Apply candidate configuration", Run: noArgs(cm.HandleCommit), Children: []*schema.Node{
+			{Keyword: "dry-run", Help: "Show the system operations commit would perform", Run: noArgs(cm.HandleCommitDryRun)},
+			{Keyword: "comment", Help: "Archive comment", Children: []*schema.Node{
+				{Value: textValue, Rest: true, Help: "Apply candidate configuration with an archive comment", Run: cm.HandleCommitComment},
+			}},
+		}},
+		{Keyword: "commit-confirm", Help: "Apply candidate configuration, roll back unless confirmed", Run: cm.HandleCommitConfirm, Children: []*schema.Node{
+			{Value: minutesValue, Help: "Roll back unless confirmed within the given minutes", Run: cm.HandleCommitConfirm},
+		}},
+		{Keyword: "validate", Help: "Check the candidate configuration", Run: noArgs(cm.HandleValidate)},
+		{Keyword: "confirm", Help: "Confirm a pending commit-confirm", Run: noArgs(cm.HandleConfirm)},
+		{Keyword: "compare", Help: "Show uncommitted changes as a diff", Run: func([]string) error {
+			return cm.HandleCompare(false)
+		}, Children: []*schema.Node{
+			{Keyword: "commands", Help: "Show uncommitted changes as commands", Run: func([]string) error {
+				return cm.HandleCompare(true)
+			}},
+			{Value: revisionValue, Help: "Compare archived revision n with the candidate", Run: cm.HandleCompareRevisions, Children: []*schema.Node{
+				{Value: otherRevision, Help: "Compare archived revisions n and m", Run: cm.HandleCompareRevisions},
+			}},
+		}},
+		{Keyword: "rollback", Help: "Load an archived revision", Children: []*schema.Node{
+			{Value: revisionValue, Help: "Load archived revision n into the candidate", Run: func(args []string) error {
+				return cm.HandleRollback(args[0])
+			}},
+		}},
+		{Keyword: "load", Help: "Replace the candidate configuration", Children: []*schema.Node{
+			{Keyword: "commands", Help: "Replay set commands from a file", Children: []*schema.Node{
+				{Value: fileValue, Help: "Replace the candidate with the commands of a file", Run: func(args []string) error {
+					return cm.HandleLoadCommands(args[0])
+				}},
+			}},
+			{Value: fileValue, Help: "Replace the candidate with a YAML configuration file", Run: func(args []string) error {
+				return cm.HandleLoad(args[0])
+			}},
+		}},
+		{Keyword: "merge", Help: "Merge a YAML configuration file into the candidate", Children: []*schema.Node{
+			{Value: fileValue, Help: "Merge a YAML configuration file into the candidate", Run: func(args []string) error {
+				return cm.HandleMerge(args[0])
+			}},
+		}},
+		{Keyword: "save", Help: "Save running configuration to boot configuration", Run: noArgs(cm.HandleSave)},
+		{Keyword: "discard", Help: "Discard uncommitted changes", Run: noError(cm.HandleDiscard)},
+		{Keyword: "exit", Help: "Leave the configuration level or configuration mode", Run: noArgs(cm.handleExit), Children: []*schema.Node{
+			{Keyword: "discard", Help: "Discard uncommitted changes and leave configuration mode", Run: noError(cm.handleExitDiscard)},
+		}},
+		{Keyword: "help", Help: "Show this help message", Run: noError(cm.printHelp)},
+		{Keyword: "?", Help: "Show this help message", Run: noError(cm.printHelp)},
+	}}
+	edit.Children = cm.editNodes(root.Child("set").Children, nil)
+	return root
+}
@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"configure/internal/config"
+	"configure/internal/system"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultConfirmMinutes is the commit-confirm timeout used when none is given
+const defaultConfirmMinutes = 10
+
+// HandleCommitConfirm applies the candidate configuration like commit, but rolls
+// back to the previous running configuration unless confirm is entered in time
+func (cm *CommandManager) HandleCommitConfirm(fields []string) error {
+	minutes := defaultConfirmMinutes
+	if len(fields) == 1 {
+		n, err := strconv.Atoi(fields[0])
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid commit-confirm timeout: %s (expected minutes >= 1)", fields[0])
+		}
+		minutes = n
+	}
+
+	// A dry run changes nothing, so there is nothing to roll back
+	if cm.dryRun {
+		_, err := cm.commit("commit-confirm")
+		return err
+	}
+
+	// Keep the configuration from before the first unconfirmed commit, so that
+	// repeated commit-confirms roll back to the last confirmed state
+	previous := cm.configManager.GetRunningConfig().Clone()
+	existing, err := cm.configManager.PendingRollback()
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		previous = existing.Previous
+	}
+
+	// Persist the record before touching the system, so an interrupted commit
+	// is still rolled back
+	pending := &config.PendingRollback{
+		ID:       fmt.Sprintf("%d-%d", time.Now().UnixNano(), os.Getpid()),
+		Deadline: time.Now().Add(time.Duration(minutes) * time.Minute),
+		Previous: previous,
+	}
+	if err := cm.configManager.SetPendingRollback(pending); err != nil {
+		return err
+	}
+	cm.pendingID = pending.ID
+
+	// Without applied changes there is nothing to roll back
+	applied, err := cm.commit("commit-confirm")
+	if err != nil || !applied {
+		if _, cerr := cm.configManager.ClaimPendingRollback(pending.ID); cerr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove commit-confirm record: %v\n", cerr)
+		}
+		cm.pendingID = ""
+		if existing != nil {
+			if serr := cm.configManager.SetPendingRollback(existing); serr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to restore pending commit-confirm %s: %v\n", existing.ID, serr)
+			}
+		}
+		return err
+	}
+
+	if cm.rollbackTimer != nil {
+		if err := cm.rollbackTimer(pending.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to start rollback timer: %v\n", err)
+			fmt.Fprintln(os.Stderr, "The rollback will happen the next time configure is started")
+		}
+	}
+
+	fmt.Printf("commit confirm will be automatically rolled back in %d minutes unless confirmed\n", minutes)
+	return nil
+}
+
+// HandleConfirm confirms a pending commit-confirm
+func (cm *CommandManager) HandleConfirm() error {
+	p, err := cm.configManager.PendingRollback()
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return fmt.Errorf("no commit-confirm is pending")
+	}
+	claimed, err := cm.configManager.ClaimPendingRollback(p.ID)
+	if err != nil {
+		return err
+	}
+	if claimed == nil {
+		return fmt.Errorf("commit-confirm was rolled back before it could be confirmed")
+	}
+
+	cm.pendingID = ""
+	fmt.Println("Commit confirmed")
+	return nil
+}
+
+// checkPendingRollback rolls back an expired commit-confirm and notices when the
+// commit-confirm issued by this session was rolled back by another process
+func (cm *CommandManager) checkPendingRollback() error {
+	p, err := cm.configManager.PendingRollback()
+	if err != nil {
+		return err
+	}
+	if p != nil && !time.Now().Before(p.Deadline) {
+		return cm.rollbackPending(p.ID)
+	}
+
+	if cm.pendingID != "" && (p == nil || p.ID != cm.pendingID) {
+		cm.pendingID = ""
+		resetCandidate := !cm.dirty()
+		changed, err := cm.configManager.ReloadRunning()
+		if err != nil {
+			return err
+		}
+		if resetCandidate {
+			cm.configManager.ResetCandidate()
+		}
+		if changed {
+			fmt.Println("commit-confirm was not confirmed in time; running configuration has been rolled back")
+		}
+	}
+	return nil
+}
+
+// rollbackPending restores the configuration recorded by the commit-confirm with
+// the given ID. It does nothing if the record was already confirmed or rolled back.
+func (cm *CommandManager) rollbackPending(id string) error {
+	p, err := cm.configManager.ClaimPendingRollback(id)
+	if err != nil || p == nil {
+		return err
+	}
+
+	rollback, err := cm.newPlan(cm.configManager.GetRunningConfig(), p.Previous)
+	if err != nil {
+		return cm.keepPending(p, err)
+	}
+	if err := rollback.Execute(cm.backend); err != nil {
+		return cm.keepPending(p, err)
+	}
+
+	// Keep uncommitted edits, but follow the rollback if there were none
+	resetCandidate := !cm.dirty()
+	if err := cm.configManager.SetRunning(p.Previous); err != nil {
+		return cm.keepPending(p, err)
+	}
+	cm.saveOriginalLinks(rollback)
+	if resetCandidate {
+		cm.configManager.ResetCandidate()
+	}
+	if cm.pendingID == id {
+		cm.pendingID = ""
+	}
+	cm.archive("rollback of unconfirmed commit-confirm")
+
+	fmt.Println("commit-confirm was not confirmed in time; running configuration has been rolled back")
+	return nil
+}
+
+// keepPending puts back a claimed commit-confirm record after its rollback
+// failed, so that the rollback is retried by the next session
+func (cm *CommandManager) keepPending(p *config.PendingRollback, err error) error {
+	if serr := cm.configManager.SetPendingRollback(p); serr != nil {
+		return fmt.Errorf("failed to roll back commit-confirm: %w; failed to restore its record: %v", err, serr)
+	}
+	return fmt.Errorf("failed to roll back commit-confirm, it will be retried: %w", err)
+}
+
+// startRollbackTimer starts a detached configure process that rolls back the
+// commit-confirm with the given ID once its deadline passes
+func startRollbackTimer(bootPath, runningPath, id string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	bootPath, err = filepath.Abs(bootPath)
+	if err != nil {
+		return err
+	}
+	runningPath, err = filepath.Abs(runningPath)
+	if err != nil {
+		return err
+	}
+
+	timer := exec.Command(exe, rollbackTimerCmd.Name(), "--boot", bootPath, "--running", runningPath, "--id", id)
+	detach(timer)
+	if err := timer.Start(); err != nil {
+		return err
+	}
+	return timer.Process.Release()
+}
+
+// runRollbackTimer waits for the deadline of the commit-confirm with the given ID
+// and rolls it back if it has not been confirmed by then
+func runRollbackTimer(bootPath, runningPath, id string) error {
+	cm := &CommandManager{
+		configManager: config.NewConfigManager(bootPath, runningPath),
+		backend:       system.NewBackend(),
+	}
+	for {
+		p, err := cm.configManager.PendingRollback()
+		if err != nil {
+			return err
+		}
+		if p == nil || p.ID != id {
+			// Confirmed, or superseded by a newer commit-confirm with its own timer
+			return nil
+		}
+		wait := time.Until(p.Deadline)
+		if wait <= 0 {
+			break
+		}
+		time.Sleep(wait)
+	}
+
+	if err := cm.configManager.Load(); err != nil {
+		return err
+	}
+	return cm.rollbackPending(id)
+}
+
+var rollbackTimerCmd = &cobra.Command{
+	Use:    "commit-confirm-timer",
+	Short:  "Roll back an unconfirmed commit-confirm once its deadline passes",
+	Hidden: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		boot, _ := cmd.Flags().GetString("boot")
+		running, _ := cmd.Flags().GetString("running")
+		id, _ := cmd.Flags().GetString("id")
+		if err := runRollbackTimer(boot, running, id); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rollbackTimerCmd.Flags().String("boot", BootConfigFile, "boot config file")
+	rollbackTimerCmd.Flags().String("running", RunningConfigFile, "running config file")
+	rollbackTimerCmd.Flags().String("id", "", "commit-confirm ID")
+	rootCmd.AddCommand(rollbackTimerCmd)
+}
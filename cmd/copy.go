@@ -9,10 +9,10 @@ import (
 
 var copyCmd = &cobra.Command{
 	Use:   "copy",
-	Short: "Copy running.config.yaml to boot.config.yaml",
+	Short: "Copy " + RunningConfigFile + " to " + BootConfigFile,
 	Run: func(cmd *cobra.Command, args []string) {
-		source := "running.config.yaml"
-		dest := "boot.config.yaml"
+		source := RunningConfigFile
+		dest := BootConfigFile
 		input, err := os.ReadFile(source)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to read %s: %v\n", source, err)
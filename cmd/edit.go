@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"configure/internal/completer"
+	"configure/internal/schema"
+)
+
+// editVerbs are the commands that take a configuration path relative to the edit level
+var editVerbs = []string{"set", "delete", "add", "edit"}
+
+// editNodes copies the nodes of the set tree that have children, so that edit
+// accepts exactly the configuration levels. template is the path to the nodes,
+// with an empty word for each value.
+func (cm *CommandManager) editNodes(nodes []*schema.Node, template []string) []*schema.Node {
+	var res []*schema.Node
+	for _, n := range nodes {
+		if len(n.Children) == 0 || n.Rest {
+			continue
+		}
+		path := append(template[:len(template):len(template)], n.Keyword)
+		res = append(res, &schema.Node{
+			Keyword: n.Keyword,
+			Value:   n.Value,
+			Help:    n.Help,
+			Run: func(args []string) error {
+				cm.handleEdit(fillPath(path, args))
+				return nil
+			},
+			Children: cm.editNodes(n.Children, path),
+		})
+	}
+	return res
+}
+
+// fillPath replaces the empty words of a path template with the given values
+func fillPath(template, values []string) []string {
+	path := make([]string, len(template))
+	for i, word := range template {
+		if word == "" && len(values) > 0 {
+			word, values = values[0], values[1:]
+		}
+		path[i] = word
+	}
+	return path
+}
+
+// resolve prefixes the path of configuration commands with the edit level.
+// Commands that are not valid at the edit level are left as typed.
+func (cm *CommandManager) resolve(fields []string) []string {
+	if len(cm.editPath) == 0 || len(fields) == 0 {
+		return fields
+	}
+	for _, verb := range editVerbs {
+		if fields[0] != verb {
+			continue
+		}
+		resolved := append(append([]string{verb}, cm.editPath...), fields[1:]...)
+		if schema.Walk(cm.tree, resolved[:1+len(cm.editPath)]) == nil {
+			return fields
+		}
+		return resolved
+	}
+	return fields
+}
+
+// completer returns a tab completer relative to the edit level
+func (cm *CommandManager) completer() *completer.CLICompleter {
+	return &completer.CLICompleter{Root: cm.tree, Resolve: cm.resolve}
+}
+
+// handleEdit enters a configuration level
+func (cm *CommandManager) handleEdit(path []string) {
+	cm.editPath = path
+	cm.updatePrompt()
+}
+
+// handleUp leaves the current configuration level
+func (cm *CommandManager) handleUp() {
+	if len(cm.editPath) == 0 {
+		fmt.Println("Already at the top level")
+		return
+	}
+	cm.editPath = cm.editPath[:len(cm.editPath)-1]
+	cm.updatePrompt()
+}
+
+// handleTop returns to the top configuration level
+func (cm *CommandManager) handleTop() {
+	cm.editPath = nil
+	cm.updatePrompt()
+}
+
+// prompt returns the prompt for the current configuration level. A * marks
+// uncommitted changes.
+func (cm *CommandManager) prompt() string {
+	mode := "(config)"
+	if cm.configManager != nil && cm.dirty() {
+		mode = "(config*)"
+	}
+	if len(cm.editPath) == 0 {
+		return mode + "# "
+	}
+	return fmt.Sprintf("[edit %s] %s# ", strings.Join(cm.editPath, " "), mode)
+}
+
+// updatePrompt shows the current configuration level in the prompt
+func (cm *CommandManager) updatePrompt() {
+	if cm.rl != nil {
+		cm.rl.SetPrompt(cm.prompt())
+	}
+}
@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"configure/internal/config"
+	"configure/internal/validator"
+)
+
+// HandleSetInterface sets interface parameters
+func (cm *CommandManager) HandleSetInterface(fields []string) error {
+	if len(fields) < 2 {
+		return fmt.Errorf("missing interface parameters")
+	}
+
+	ifaceName := fields[0]
+	param := fields[1]
+	value := ""
+	if len(fields) > 2 {
+		value = fields[2]
+	}
+
+	iface := cm.configManager.GetConfig().Interfaces[ifaceName]
+	if param != "virtual" && !iface.Virtual {
+		if err := cm.checkInterfaceExists(ifaceName); err != nil {
+			return err
+		}
+	}
+
+	switch param {
+	case "virtual", "disable":
+		if param == "virtual" {
+			iface.Virtual = true
+		} else {
+			iface.Disable = true
+		}
+		cm.configManager.SetInterface(ifaceName, iface)
+		fmt.Printf("Set interface %s %s\n", ifaceName, param)
+		return nil
+	case "offload":
+		if err := validator.ValidateOffload(value); err != nil {
+			return fmt.Errorf("invalid offload feature: %w", err)
+		}
+		if iface.HasOffload(value) {
+			return fmt.Errorf("interface %s already has offload %s enabled", ifaceName, value)
+		}
+		iface.Offload = append(append([]string(nil), iface.Offload...), value)
+		cm.configManager.SetInterface(ifaceName, iface)
+		fmt.Printf("Enabled interface %s offload %s\n", ifaceName, value)
+		return nil
+	case "address":
+		if value != config.AddressDHCP && value != config.AddressDHCPv6 {
+			if err := validator.ValidateAddressWithPrefix(value); err != nil {
+				return fmt.Errorf("invalid interface address: %w", err)
+			}
+		}
+		if iface.HasAddress(value) {
+			return fmt.Errorf("interface %s already has address %s", ifaceName, value)
+		}
+		iface.Addresses = append(append(config.AddressList(nil), iface.Addresses...), value)
+		cm.configManager.SetInterface(ifaceName, iface)
+		fmt.Printf("Added interface %s address %s\n", ifaceName, value)
+		return nil
+	case "ipv6":
+		if value != "autoconf" {
+			return fmt.Errorf("unknown interface ipv6 parameter: %s", value)
+		}
+		iface.IPv6.Autoconf = true
+		cm.configManager.SetInterface(ifaceName, iface)
+		fmt.Printf("Set interface %s ipv6 address autoconf\n", ifaceName)
+		return nil
+	case "mac":
+		if err := validator.ValidateMACAddress(value); err != nil {
+			return fmt.Errorf("invalid MAC address: %w", err)
+		}
+		iface.MAC = value
+	case "description":
+		if err := validator.ValidateDescription(value); err != nil {
+			return fmt.Errorf("invalid description: %w", err)
+		}
+		iface.Description = value
+	case "mtu":
+		if err := validator.ValidateMTU(value); err != nil {
+			return fmt.Errorf("invalid MTU: %w", err)
+		}
+		iface.MTU, _ = strconv.Atoi(value)
+	case "speed":
+		if err := validator.ValidateSpeed(value); err != nil {
+			return fmt.Errorf("invalid speed: %w", err)
+		}
+		iface.Speed = value
+	case "duplex":
+		if err := validator.ValidateDuplex(value); err != nil {
+			return fmt.Errorf("invalid duplex: %w", err)
+		}
+		iface.Duplex = value
+	default:
+		return fmt.Errorf("unknown interface parameter: %s", param)
+	}
+
+	cm.configManager.SetInterface(ifaceName, iface)
+	fmt.Printf("Set interface %s %s to %s\n", ifaceName, param, value)
+	return nil
+}
+
+// HandleDeleteInterface removes an interface or one of its parameters
+func (cm *CommandManager) HandleDeleteInterface(fields []string) error {
+	ifaceName := fields[0]
+	if len(fields) == 1 {
+		if err := cm.configManager.DeleteInterface(ifaceName); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted interface %s\n", ifaceName)
+		return nil
+	}
+	if len(fields) > 3 {
+		return fmt.Errorf("too many arguments for delete interfaces")
+	}
+
+	param := fields[1]
+	value := ""
+	if len(fields) > 2 {
+		value = fields[2]
+	}
+	iface, ok := cm.configManager.GetConfig().Interfaces[ifaceName]
+	if !ok {
+		return fmt.Errorf("interface %s is not configured", ifaceName)
+	}
+	switch param {
+	case "address":
+		if len(iface.Addresses) == 0 {
+			return fmt.Errorf("interface %s has no address configured", ifaceName)
+		}
+		if value == "" {
+			iface.Addresses = nil
+			break
+		}
+		if !iface.HasAddress(value) {
+			return fmt.Errorf("interface %s has no address %s configured", ifaceName, value)
+		}
+		var addrs config.AddressList
+		for _, addr := range iface.Addresses {
+			if addr != value {
+				addrs = append(addrs, addr)
+			}
+		}
+		iface.Addresses = addrs
+		cm.configManager.SetInterface(ifaceName, iface)
+		fmt.Printf("Deleted interface %s address %s\n", ifaceName, value)
+		return nil
+	case "ipv6":
+		if value != "autoconf" {
+			return fmt.Errorf("unknown interface ipv6 parameter: %s", value)
+		}
+		if !iface.IPv6.Autoconf {
+			return fmt.Errorf("interface %s has no ipv6 address autoconf configured", ifaceName)
+		}
+		iface.IPv6.Autoconf = false
+		cm.configManager.SetInterface(ifaceName, iface)
+		fmt.Printf("Deleted interface %s ipv6 address autoconf\n", ifaceName)
+		return nil
+	case "mac":
+		if iface.MAC == "" {
+			return fmt.Errorf("interface %s has no MAC address configured", ifaceName)
+		}
+		iface.MAC = ""
+	case "virtual":
+		if !iface.Virtual {
+			return fmt.Errorf("interface %s is not declared virtual", ifaceName)
+		}
+		iface.Virtual = false
+	case "disable":
+		if !iface.Disable {
+			return fmt.Errorf("interface %s is not disabled", ifaceName)
+		}
+		iface.Disable = false
+	case "description":
+		if iface.Description == "" {
+			return fmt.Errorf("interface %s has no description configured", ifaceName)
+		}
+		iface.Description = ""
+	case "mtu":
+		if iface.MTU == 0 {
+			return fmt.Errorf("interface %s has no MTU configured", ifaceName)
+		}
+		iface.MTU = 0
+	case "speed":
+		if iface.Speed == "" {
+			return fmt.Errorf("interface %s has no speed configured", ifaceName)
+		}
+		iface.Speed = ""
+	case "duplex":
+		if iface.Duplex == "" {
+			return fmt.Errorf("interface %s has no duplex configured", ifaceName)
+		}
+		iface.Duplex = ""
+	case "offload":
+		if len(iface.Offload) == 0 {
+			return fmt.Errorf("interface %s has no offload features enabled", ifaceName)
+		}
+		if value == "" {
+			iface.Offload = nil
+			break
+		}
+		if !iface.HasOffload(value) {
+			return fmt.Errorf("interface %s has no offload %s enabled", ifaceName, value)
+		}
+		var features []string
+		for _, feature := range iface.Offload {
+			if feature != value {
+				features = append(features, feature)
+			}
+		}
+		iface.Offload = features
+		cm.configManager.SetInterface(ifaceName, iface)
+		fmt.Printf("Deleted interface %s offload %s\n", ifaceName, value)
+		return nil
+	default:
+		return fmt.Errorf("unknown interface parameter: %s", param)
+	}
+
+	cm.configManager.SetInterface(ifaceName, iface)
+	fmt.Printf("Deleted interface %s %s\n", ifaceName, param)
+	return nil
+}
+
+// checkInterfaceExists returns an error if an interface does not exist on the system.
+// If the interfaces cannot be listed, it only warns.
+func (cm *CommandManager) checkInterfaceExists(name string) error {
+	links, err := cm.backend.Links()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cannot verify interface %s: %v\n", name, err)
+		return nil
+	}
+	for _, link := range links {
+		if link == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("interface %s does not exist; use 'set interfaces %s virtual' to configure it anyway", name, name)
+}
+
+// interfaceNames returns the interfaces present on the system and those in the
+// candidate configuration, for completion
+func (cm *CommandManager) interfaceNames() []string {
+	names, _ := cm.backend.Links()
+	for name := range cm.configManager.GetConfig().Interfaces {
+		names = append(names, name)
+	}
+	return names
+}
+
+// handleShowInterfaces displays the current interface status
+func (cm *CommandManager) handleShowInterfaces() {
+	cfg := cm.configManager.GetConfig()
+	fmt.Println("Interfaces:")
+	for name, iface := range cfg.Interfaces {
+		fmt.Printf("  %s:\n", name)
+		if iface.Description != "" {
+			fmt.Printf("    description: %s\n", iface.Description)
+		}
+		if iface.Disable {
+			fmt.Println("    state: disabled")
+		} else {
+			fmt.Println("    state: enabled")
+		}
+		for _, addr := range iface.Addresses {
+			fmt.Printf("    address: %s\n", addr)
+		}
+		if iface.IPv6.Autoconf {
+			fmt.Println("    ipv6 address: autoconf")
+		}
+		if iface.MAC != "" {
+			fmt.Printf("    mac: %s\n", iface.MAC)
+		}
+		if iface.MTU != 0 {
+			fmt.Printf("    mtu: %d\n", iface.MTU)
+		}
+		if speed, duplex := iface.LinkMode(); speed != config.LinkAuto || duplex != config.LinkAuto {
+			fmt.Printf("    speed: %s duplex: %s\n", speed, duplex)
+		}
+		if len(iface.Offload) > 0 {
+			fmt.Printf("    offload: %s\n", strings.Join(iface.Offload, " "))
+		}
+		if iface.Virtual {
+			fmt.Println("    virtual")
+		}
+	}
+}
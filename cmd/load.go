@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+
+	"configure/internal/config"
+	"configure/internal/lexer"
+	"configure/internal/schema"
+)
+
+// loadVerbs are the commands allowed in a command file
+var loadVerbs = map[string]bool{"set": true, "add": true, "delete": true}
+
+// handleShowConfigurationCommands prints the candidate configuration as the
+// commands that recreate it
+func (cm *CommandManager) handleShowConfigurationCommands() {
+	for _, line := range config.Commands(cm.configManager.GetConfig()) {
+		fmt.Println(line)
+	}
+}
+
+// HandleLoad replaces the candidate configuration with a YAML configuration file
+func (cm *CommandManager) HandleLoad(path string) error {
+	cfg, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+	if err := cm.validateLoaded(cfg); err != nil {
+		return err
+	}
+	cm.configManager.LoadCandidate(cfg)
+	fmt.Printf("Loaded %s into the candidate configuration; use compare to review and commit to apply it\n", path)
+	return nil
+}
+
+// HandleMerge overlays a YAML configuration file onto the candidate configuration
+func (cm *CommandManager) HandleMerge(path string) error {
+	overlay, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+	cfg := cm.configManager.GetConfig().Clone()
+	cfg.Merge(overlay)
+	if err := cm.validateLoaded(cfg); err != nil {
+		return err
+	}
+	cm.configManager.LoadCandidate(cfg)
+	fmt.Printf("Merged %s into the candidate configuration; use compare to review and commit to apply it\n", path)
+	return nil
+}
+
+// readConfigFile reads and parses a YAML configuration file
+func readConfigFile(path string) (*config.Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	cfg, err := config.ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// validateLoaded checks a configuration before it becomes the candidate,
+// with the same checks the set commands apply
+func (cm *CommandManager) validateLoaded(cfg *config.Config) error {
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	for _, name := range sortedInterfaceNames(cfg) {
+		if !cfg.Interfaces[name].Virtual {
+			if err := cm.checkInterfaceExists(name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sortedInterfaceNames returns the names of the configured interfaces in sorted order
+func sortedInterfaceNames(cfg *config.Config) []string {
+	names := make([]string, 0, len(cfg.Interfaces))
+	for name := range cfg.Interfaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HandleLoadCommands replaces the candidate configuration with the result of
+// replaying the set, add and delete commands of a file on an empty
+// configuration. On error the candidate is left unchanged.
+func (cm *CommandManager) HandleLoadCommands(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open command file: %w", err)
+	}
+	defer f.Close()
+
+	previous := cm.configManager.GetConfig().Clone()
+	cm.configManager.LoadCandidate(config.NewEmptyConfig())
+
+	scanner := bufio.NewScanner(f)
+	count := 0
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		fields, err := lexer.Split(scanner.Text())
+		if err == nil && len(fields) == 0 {
+			continue
+		}
+		if err == nil && !loadVerbs[fields[0]] {
+			err = fmt.Errorf("only set, add and delete commands can be loaded")
+		}
+		if err == nil {
+			err = schema.Execute(cm.tree, fields)
+		}
+		if err != nil {
+			cm.configManager.LoadCandidate(previous)
+			return fmt.Errorf("%s: line %d: %w", path, lineNo, err)
+		}
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		cm.configManager.LoadCandidate(previous)
+		return fmt.Errorf("failed to read command file: %w", err)
+	}
+
+	fmt.Printf("Loaded %d commands from %s into the candidate configuration\n", count, path)
+	return nil
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"configure/internal/config"
+	"configure/internal/schema"
+	"configure/internal/system"
+
+	"github.com/spf13/cobra"
+)
+
+// interactiveOnly are the top-level commands that only make sense in the
+// interactive mode and get no one-shot subcommand
+var interactiveOnly = map[string]bool{
+	"edit": true,
+	"up":   true,
+	"top":  true,
+	"exit": true,
+	"help": true,
+	"?":    true,
+}
+
+// oneShotCommands returns a cobra subcommand for every top-level command of the
+// command schema. Each runs a single command through HandleCommand, so that
+// e.g. "configure set dns 8.8.8.8" works from the shell. Uncommitted changes
+// are kept in the persisted candidate until a later commit; the interactive
+// and batch modes do not see them.
+func oneShotCommands() []*cobra.Command {
+	var cmds []*cobra.Command
+	for _, node := range (&CommandManager{}).commandTree().Children {
+		if interactiveOnly[node.Keyword] {
+			continue
+		}
+		keyword := node.Keyword
+		cmds = append(cmds, &cobra.Command{
+			Use:   keyword + " " + usageArgs(node),
+			Short: node.Help,
+			Args:  cobra.ArbitraryArgs,
+			Run: func(cmd *cobra.Command, args []string) {
+				cm := newCLICommandManager(cmd)
+				if err := cm.HandleOneShot(append([]string{keyword}, args...)); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+			},
+			ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+				return completeArgs(append([]string{keyword}, args...), toComplete), cobra.ShellCompDirectiveNoFileComp
+			},
+		})
+	}
+	return cmds
+}
+
+// HandleOneShot runs a single command on the candidate persisted by earlier
+// one-shot commands and persists the result for the next one. Persisted
+// changes made against an older running configuration are refused, except by
+// discard, which drops them.
+func (cm *CommandManager) HandleOneShot(fields []string) error {
+	err := cm.configManager.RestoreCandidate()
+	switch {
+	case errors.Is(err, config.ErrStaleCandidate) && fields[0] == "discard":
+	case errors.Is(err, config.ErrStaleCandidate):
+		return fmt.Errorf("%w; run discard to drop them", err)
+	case err != nil:
+		return err
+	}
+
+	err = cm.HandleCommand(fields)
+	if saveErr := cm.configManager.SaveCandidate(); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	return err
+}
+
+// usageArgs returns the usage placeholder of the arguments of a top-level command
+func usageArgs(node *schema.Node) string {
+	switch {
+	case len(node.Children) == 0:
+		return ""
+	case node.Run != nil:
+		return "[args...]"
+	default:
+		return "args..."
+	}
+}
+
+// completeArgs returns the shell completions of a partial command, with the
+// same candidates as the interactive completion
+func completeArgs(words []string, toComplete string) []string {
+	cm := &CommandManager{
+		configManager: config.NewConfigManager(BootConfigFile, RunningConfigFile),
+		backend:       system.NewBackend(),
+	}
+	// Completion must not create config files, so nothing is written
+	cm.configManager.LoadReadOnly()
+	if err := cm.configManager.RestoreCandidate(); err != nil {
+		cm.configManager.ResetCandidate()
+	}
+	cm.tree = cm.commandTree()
+
+	node := schema.Walk(cm.tree, words)
+	if node == nil {
+		return nil
+	}
+	var res []string
+	for _, c := range schema.Candidates(node, toComplete) {
+		res = append(res, c.Word+"\t"+strings.ReplaceAll(c.Help, "\t", " "))
+	}
+	return res
+}
+
+func init() {
+	rootCmd.AddCommand(oneShotCommands()...)
+}
@@ -14,7 +14,12 @@ func prettyPrintConfig(cfg *config.Config) {
 	fmt.Println("interfaces:")
 	for name, iface := range cfg.Interfaces {
 		fmt.Printf("  %s:\n", name)
-		fmt.Printf("    address: %s\n", iface.Address)
+		if len(iface.Addresses) > 0 {
+			fmt.Println("    address:")
+			for _, addr := range iface.Addresses {
+				fmt.Printf("      - %s\n", addr)
+			}
+		}
 	}
 	if len(cfg.DNS) > 0 {
 		fmt.Println("dns:")
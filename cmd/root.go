@@ -1,14 +1,18 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"strings"
 
 	"configure/internal/completer"
 	"configure/internal/config"
+	"configure/internal/lexer"
+	"configure/internal/plan"
+	"configure/internal/schema"
+	"configure/internal/system"
 	"configure/internal/validator"
 	"configure/internal/version"
 
@@ -20,6 +24,23 @@ import (
 type CommandManager struct {
 	configManager *config.ConfigManager
 	rl            *readline.Instance
+	backend       system.Backend
+	// tree is the command schema bound to the handlers of this CommandManager
+	tree *schema.Node
+	// editPath is the configuration level entered with edit, empty at the top
+	editPath []string
+	// exited is set by exit at the top level to end the session
+	exited bool
+	// exitRefused is set when end of input was refused, so that repeating it
+	// right away ends the session anyway
+	exitRefused bool
+	// dryRun makes commits print their plan instead of applying it
+	dryRun bool
+	// pendingID is the ID of the commit-confirm issued by this session, if any
+	pendingID string
+	// rollbackTimer starts the process that rolls back an unconfirmed commit.
+	// When nil, the rollback only happens the next time the CLI checks the record.
+	rollbackTimer func(id string) error
 }
 
 // NewCommandManager creates a new CommandManager instance with the specified configuration files
@@ -29,21 +50,16 @@ func NewCommandManager(bootPath, runningPath string) (*CommandManager, error) {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
-	rl, err := readline.NewEx(&readline.Config{
-		Prompt:          "(config)# ",
-		HistoryFile:     ".nehv_configure_history",
-		InterruptPrompt: "^C",
-		EOFPrompt:       "exit",
-		AutoComplete:    &completer.CLICompleter{},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize readline: %w", err)
+	cmdManager := &CommandManager{
+		configManager: cm,
+		backend:       system.NewBackend(),
 	}
+	cmdManager.tree = cmdManager.commandTree()
 
-	return &CommandManager{
-		configManager: cm,
-		rl:            rl,
-	}, nil
+	if err := cmdManager.checkPendingRollback(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	return cmdManager, nil
 }
 
 // Close releases resources used by the CommandManager
@@ -55,17 +71,30 @@ func (cm *CommandManager) Close() {
 
 // Execute starts the interactive configuration mode
 func (cm *CommandManager) Execute() error {
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:          cm.prompt(),
+		HistoryFile:     ".nehv_configure_history",
+		InterruptPrompt: "^C",
+		EOFPrompt:       "exit",
+		AutoComplete:    cm.completer(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize readline: %w", err)
+	}
+	cm.rl = rl
 	defer cm.Close()
 
-	for {
+	for !cm.exited {
 		line, err := cm.rl.Readline()
-		if err == readline.ErrInterrupt {
-			if len(line) == 0 {
-				break
+		if err == io.EOF || (err == readline.ErrInterrupt && len(line) == 0) {
+			if err := cm.HandleEndOfInput(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			}
 			continue
-		} else if err == io.EOF {
-			break
+		}
+		cm.exitRefused = false
+		if err == readline.ErrInterrupt {
+			continue
 		}
 
 		line = strings.TrimSpace(line)
@@ -73,7 +102,11 @@ func (cm *CommandManager) Execute() error {
 			continue
 		}
 
-		fields := splitFields(line)
+		fields, err := lexer.Split(line)
+		if err != nil {
+			printLexError(line, err)
+			continue
+		}
 		if len(fields) == 0 {
 			continue
 		}
@@ -81,6 +114,7 @@ func (cm *CommandManager) Execute() error {
 		if err := cm.HandleCommand(fields); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		}
+		cm.updatePrompt()
 	}
 
 	return nil
@@ -88,40 +122,24 @@ func (cm *CommandManager) Execute() error {
 
 // HandleCommand processes the command based on the input fields
 func (cm *CommandManager) HandleCommand(fields []string) error {
-	switch {
-	case len(fields) == 1 && fields[0] == "exit":
-		os.Exit(0)
-	case len(fields) == 1 && (fields[0] == "help" || fields[0] == "?"):
-		cm.printHelp()
-	case len(fields) == 1 && fields[0] == "save":
-		return cm.HandleSave()
-	case len(fields) == 1 && fields[0] == "commit":
-		return cm.HandleCommit()
-	case len(fields) == 3 && fields[0] == "set" && fields[1] == "dns":
-		return cm.HandleSetDNS(fields[2])
-	case len(fields) == 3 && fields[0] == "add" && fields[1] == "dns":
-		return cm.HandleAddDNS(fields[2])
-	case len(fields) == 2 && fields[0] == "show" && fields[1] == "dns":
-		cm.handleShowDNS()
-	case len(fields) == 2 && fields[0] == "show" && fields[1] == "config":
-		cm.handleShowConfig()
-	case len(fields) == 2 && fields[0] == "show" && fields[1] == "interfaces":
-		cm.handleShowInterfaces()
-	case len(fields) == 2 && fields[0] == "show" && fields[1] == "version":
-		cm.handleShowVersion()
-	case len(fields) >= 4 && fields[0] == "set" && fields[1] == "interfaces":
-		return cm.HandleSetInterface(fields[2:])
-	case len(fields) == 5 && fields[0] == "set" && fields[1] == "ip" && fields[2] == "route" && fields[3] == "default" && fields[4] == "via":
-		return cm.HandleSetDefaultRoute(fields[5:])
-	default:
-		return fmt.Errorf("unknown command: %s", strings.Join(fields, " "))
+	cm.exitRefused = false
+	if err := cm.checkPendingRollback(); err != nil {
+		return err
 	}
-	return nil
+
+	fields = cm.resolve(fields)
+
+	// A trailing ? shows the help of the command typed so far
+	if len(fields) > 1 && fields[len(fields)-1] == "?" {
+		return cm.printContextHelp(fields[:len(fields)-1])
+	}
+
+	return schema.Execute(cm.tree, fields)
 }
 
 // Configuration Management Methods
 
-// HandleSave saves the current configuration to both boot and running config files
+// HandleSave saves the running configuration to the boot config file
 func (cm *CommandManager) HandleSave() error {
 	if err := cm.configManager.Save(); err != nil {
 		return fmt.Errorf("failed to save configuration: %w", err)
@@ -130,36 +148,158 @@ func (cm *CommandManager) HandleSave() error {
 	return nil
 }
 
-// HandleCommit applies the current configuration to the system
+// HandleCommit applies the candidate configuration to the system and,
+// on success, makes it the running configuration
 func (cm *CommandManager) HandleCommit() error {
-	cfg := cm.configManager.GetConfig()
+	_, err := cm.commit("")
+	return err
+}
+
+// commit applies and commits the candidate configuration and stores the
+// result in the commit archive with the given comment. In dry-run mode it
+// only prints the planned system operations. It reports whether the
+// configuration was applied, which is not the case without changes.
+func (cm *CommandManager) commit(comment string) (bool, error) {
+	if cm.configManager.GetConfig().Equal(cm.configManager.GetRunningConfig()) {
+		fmt.Println("No configuration changes to commit")
+		return false, nil
+	}
+	if err := cm.configManager.GetConfig().Validate(); err != nil {
+		return false, fmt.Errorf("commit failed, configuration is invalid:\n%w", err)
+	}
 
-	// Write resolv.conf
-	if err := cm.writeResolvConf(cfg.DNS); err != nil {
-		return fmt.Errorf("failed to write resolv.conf: %w", err)
+	p, err := cm.newPlan(cm.configManager.GetRunningConfig(), cm.configManager.GetConfig())
+	if err != nil {
+		return false, err
+	}
+	if cm.dryRun {
+		printPlan(p)
+		return false, nil
 	}
 
-	// Restart services
-	if err := cm.restartServices(); err != nil {
-		return fmt.Errorf("failed to restart services: %w", err)
+	if err := p.Execute(cm.backend); err != nil {
+		return false, fmt.Errorf("commit failed, running configuration unchanged: %w", err)
 	}
 
-	// Set default route
-	if cfg.DefaultRoute != "" {
-		if err := cm.setDefaultRoute(cfg.DefaultRoute); err != nil {
-			return fmt.Errorf("failed to set default route: %w", err)
+	// The system must keep matching the running configuration
+	if err := cm.configManager.Commit(); err != nil {
+		if undoErr := p.Undo(cm.backend); undoErr != nil {
+			return false, fmt.Errorf("failed to commit configuration: %w; undo of applied operations failed: %v", err, undoErr)
 		}
+		return false, fmt.Errorf("failed to commit configuration, applied operations were undone: %w", err)
 	}
+	cm.saveOriginalLinks(p)
+	cm.archive(comment)
 
 	fmt.Println("Configuration applied successfully")
+	return true, nil
+}
+
+// newPlan returns the plan from the old to the new configuration, using the
+// original link settings recorded by earlier commits
+func (cm *CommandManager) newPlan(old, new *config.Config) (*plan.Plan, error) {
+	original, err := cm.configManager.OriginalLinks()
+	if err != nil {
+		return nil, err
+	}
+	return plan.New(old, new, original), nil
+}
+
+// saveOriginalLinks persists the original link settings of an executed plan.
+// The plan has been applied already, so a failure is only reported.
+func (cm *CommandManager) saveOriginalLinks(p *plan.Plan) {
+	if err := cm.configManager.SetOriginalLinks(p.Original); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// HandleValidate checks the candidate configuration without committing it
+func (cm *CommandManager) HandleValidate() error {
+	if err := cm.configManager.GetConfig().Validate(); err != nil {
+		return fmt.Errorf("configuration is invalid:\n%w", err)
+	}
+	fmt.Println("Configuration is valid")
+	return nil
+}
+
+// HandleCommitDryRun prints the system operations a commit would perform
+// without changing the system or the running configuration
+func (cm *CommandManager) HandleCommitDryRun() error {
+	p, err := cm.newPlan(cm.configManager.GetRunningConfig(), cm.configManager.GetConfig())
+	if err != nil {
+		return err
+	}
+	printPlan(p)
+	return nil
+}
+
+// printPlan prints the steps of a plan
+func printPlan(p *plan.Plan) {
+	if len(p.Steps) == 0 {
+		fmt.Println("No system operations planned")
+		return
+	}
+	fmt.Println("Planned system operations:")
+	for _, line := range strings.Split(strings.TrimSuffix(p.String(), "\n"), "\n") {
+		fmt.Println("  " + line)
+	}
+}
+
+// HandleCompare shows the difference between the candidate and running configuration,
+// either as a unified diff or as the set/delete commands that produce it
+func (cm *CommandManager) HandleCompare(commands bool) error {
+	running, err := cm.configManager.ReadRunningConfig()
+	if err != nil {
+		return err
+	}
+	candidate := cm.configManager.GetConfig()
+
+	if commands {
+		cmds := config.CompareCommands(config.Diff(running, candidate))
+		if len(cmds) == 0 {
+			fmt.Println("No changes between candidate and running configurations")
+			return nil
+		}
+		for _, c := range cmds {
+			fmt.Println(c)
+		}
+		return nil
+	}
+
+	diff, err := config.UnifiedDiff("running", "candidate", running, candidate)
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		fmt.Println("No changes between candidate and running configurations")
+		return nil
+	}
+	fmt.Print(diff)
 	return nil
 }
 
 // DNS Configuration Methods
 
+// HandleSetHostname sets the host name
+func (cm *CommandManager) HandleSetHostname(name string) error {
+	cm.configManager.SetHostname(name)
+	fmt.Printf("Set hostname: %s\n", name)
+	return nil
+}
+
+// HandleDeleteHostname removes the host name
+func (cm *CommandManager) HandleDeleteHostname() error {
+	if cm.configManager.GetConfig().Hostname == "" {
+		return fmt.Errorf("hostname is not configured")
+	}
+	cm.configManager.SetHostname("")
+	fmt.Println("Deleted hostname")
+	return nil
+}
+
 // HandleSetDNS sets the DNS servers
 func (cm *CommandManager) HandleSetDNS(dnsAddr string) error {
-	if err := validator.ValidateDNSAddress(dnsAddr); err != nil {
+	if err := validator.ValidateHostAddress(dnsAddr); err != nil {
 		return fmt.Errorf("invalid DNS address: %w", err)
 	}
 	cm.configManager.SetDNS([]string{dnsAddr})
@@ -169,7 +309,7 @@ func (cm *CommandManager) HandleSetDNS(dnsAddr string) error {
 
 // HandleAddDNS adds a DNS server
 func (cm *CommandManager) HandleAddDNS(dnsAddr string) error {
-	if err := validator.ValidateDNSAddress(dnsAddr); err != nil {
+	if err := validator.ValidateHostAddress(dnsAddr); err != nil {
 		return fmt.Errorf("invalid DNS address: %w", err)
 	}
 	cm.configManager.AddDNS(dnsAddr)
@@ -177,39 +317,20 @@ func (cm *CommandManager) HandleAddDNS(dnsAddr string) error {
 	return nil
 }
 
-// Interface Configuration Methods
-
-// HandleSetInterface sets interface parameters
-func (cm *CommandManager) HandleSetInterface(fields []string) error {
-	if len(fields) < 2 {
-		return fmt.Errorf("missing interface parameters")
+// HandleDeleteDNS removes a DNS server, or all DNS servers if none is given
+func (cm *CommandManager) HandleDeleteDNS(fields []string) error {
+	if len(fields) > 1 {
+		return fmt.Errorf("too many arguments for delete dns")
 	}
-
-	ifaceName := fields[0]
-	param := fields[1]
-	value := ""
-	if len(fields) > 2 {
-		value = fields[2]
+	if len(fields) == 0 {
+		cm.configManager.DeleteDNS("")
+		fmt.Println("Deleted all DNS servers")
+		return nil
 	}
-
-	iface := cm.configManager.GetConfig().Interfaces[ifaceName]
-	switch param {
-	case "address":
-		if err := validator.ValidateIPAddress(value); err != nil {
-			return fmt.Errorf("invalid IP address: %w", err)
-		}
-		iface.Address = value
-	case "mac":
-		if err := validator.ValidateMACAddress(value); err != nil {
-			return fmt.Errorf("invalid MAC address: %w", err)
-		}
-		iface.MAC = value
-	default:
-		return fmt.Errorf("unknown interface parameter: %s", param)
+	if err := cm.configManager.DeleteDNS(fields[0]); err != nil {
+		return err
 	}
-
-	cm.configManager.SetInterface(ifaceName, iface)
-	fmt.Printf("Set interface %s %s to %s\n", ifaceName, param, value)
+	fmt.Printf("Deleted DNS: %s\n", fields[0])
 	return nil
 }
 
@@ -220,8 +341,8 @@ func (cm *CommandManager) HandleSetDefaultRoute(fields []string) error {
 	}
 
 	ipAddr := fields[0]
-	if err := validator.ValidateIPAddress(ipAddr); err != nil {
-		return fmt.Errorf("invalid IP address: %w", err)
+	if err := validator.ValidateGateway(ipAddr); err != nil {
+		return fmt.Errorf("invalid gateway: %w", err)
 	}
 
 	cm.configManager.SetDefaultRoute(ipAddr)
@@ -229,6 +350,15 @@ func (cm *CommandManager) HandleSetDefaultRoute(fields []string) error {
 	return nil
 }
 
+// HandleDeleteDefaultRoute removes the default route
+func (cm *CommandManager) HandleDeleteDefaultRoute() error {
+	if err := cm.configManager.DeleteDefaultRoute(); err != nil {
+		return err
+	}
+	fmt.Println("Deleted default route")
+	return nil
+}
+
 // Display Methods
 
 // handleShowDNS displays the current DNS settings
@@ -243,19 +373,6 @@ func (cm *CommandManager) handleShowConfig() {
 	cm.prettyPrintConfig(cfg)
 }
 
-// handleShowInterfaces displays the current interface status
-func (cm *CommandManager) handleShowInterfaces() {
-	cfg := cm.configManager.GetConfig()
-	fmt.Println("Interfaces:")
-	for name, iface := range cfg.Interfaces {
-		fmt.Printf("  %s:\n", name)
-		fmt.Printf("    address: %s\n", iface.Address)
-		if iface.MAC != "" {
-			fmt.Printf("    mac: %s\n", iface.MAC)
-		}
-	}
-}
-
 // handleShowVersion displays the version information
 func (cm *CommandManager) handleShowVersion() {
 	fmt.Printf("Version: %s\n", version.Version)
@@ -263,47 +380,94 @@ func (cm *CommandManager) handleShowVersion() {
 	fmt.Printf("Author: %s\n", version.Author)
 }
 
-// System Operation Methods
+// Helper Methods
 
-// writeResolvConf writes DNS settings to /etc/resolv.conf
-func (cm *CommandManager) writeResolvConf(dnsServers []string) error {
-	content := "nameserver " + strings.Join(dnsServers, "\nnameserver ")
-	return os.WriteFile("/etc/resolv.conf", []byte(content), 0644)
+// printHelp prints the help message
+func (cm *CommandManager) printHelp() {
+	usages := schema.Usages(cm.tree)
+	width := 0
+	for _, u := range usages {
+		width = max(width, len(u.Command))
+	}
+	fmt.Println("Available commands:")
+	for _, u := range usages {
+		fmt.Printf("  %-*s  %s\n", width, u.Command, u.Help)
+	}
 }
 
-// restartServices restarts the necessary services
-func (cm *CommandManager) restartServices() error {
-	if err := exec.Command("sudo", "systemctl", "restart", "resolvconf.service").Run(); err != nil {
+// printContextHelp prints the possible completions of a partial command
+func (cm *CommandManager) printContextHelp(fields []string) error {
+	node := schema.Walk(cm.tree, fields)
+	if node == nil {
+		return fmt.Errorf("unknown command: %s", strings.Join(fields, " "))
+	}
+	fmt.Println("Possible completions:")
+	for _, line := range schema.Help(node) {
+		fmt.Println("  " + line)
+	}
+	return nil
+}
+
+// handleExit leaves the current configuration level, or configuration mode at
+// the top. Configuration mode is only left without uncommitted or unsaved changes.
+func (cm *CommandManager) handleExit() error {
+	if len(cm.editPath) > 0 {
+		cm.handleUp()
+		return nil
+	}
+	return cm.leave()
+}
+
+// HandleEndOfInput leaves configuration mode on Ctrl-D, or Ctrl-C at an empty
+// line. Like exit it refuses to drop uncommitted or unsaved changes, unless it
+// is repeated right away.
+func (cm *CommandManager) HandleEndOfInput() error {
+	if cm.exitRefused {
+		cm.handleExitDiscard()
+		return nil
+	}
+	if err := cm.leave(); err != nil {
+		cm.exitRefused = true
+		return fmt.Errorf("%w; press Ctrl-D again to exit anyway", err)
+	}
+	return nil
+}
+
+// leave ends the session unless there are uncommitted or unsaved changes
+func (cm *CommandManager) leave() error {
+	if cm.dirty() {
+		return fmt.Errorf("uncommitted changes; use commit, or exit discard to discard them")
+	}
+	saved, err := cm.configManager.Saved()
+	if err != nil {
 		return err
 	}
-	return exec.Command("sudo", "systemctl", "restart", "systemd-resolved.service").Run()
+	if !saved {
+		return fmt.Errorf("running configuration is not saved; use save, or exit discard to exit anyway")
+	}
+	cm.exited = true
+	return nil
 }
 
-// setDefaultRoute sets the default route in the system
-func (cm *CommandManager) setDefaultRoute(route string) error {
-	return exec.Command("sudo", "ip", "route", "add", "default", "via", route).Run()
+// handleExitDiscard discards uncommitted changes and leaves configuration mode
+func (cm *CommandManager) handleExitDiscard() {
+	cm.configManager.ResetCandidate()
+	cm.exited = true
 }
 
-// Helper Methods
+// HandleDiscard reverts the candidate configuration to the running configuration
+func (cm *CommandManager) HandleDiscard() {
+	if !cm.dirty() {
+		fmt.Println("No uncommitted changes")
+		return
+	}
+	cm.configManager.ResetCandidate()
+	fmt.Println("Discarded uncommitted changes")
+}
 
-// printHelp prints the help message
-func (cm *CommandManager) printHelp() {
-	fmt.Println("Available commands:")
-	fmt.Println("  set dns <address>           Set DNS address")
-	fmt.Println("  add dns <address>           Add DNS address")
-	fmt.Println("  set interfaces <iface> <param> <value>  Set interface parameters")
-	fmt.Println("    Parameters:")
-	fmt.Println("      address <ip/mask>       Set interface IP address")
-	fmt.Println("      mac <address>           Set interface MAC address")
-	fmt.Println("  set ip route default via <ip>  Set default route")
-	fmt.Println("  show dns                     Show current DNS settings")
-	fmt.Println("  show config                  Show current configuration")
-	fmt.Println("  show interfaces              Show interface status")
-	fmt.Println("  show version                 Show version information")
-	fmt.Println("  save                         Save current configuration")
-	fmt.Println("  commit                       Apply current configuration")
-	fmt.Println("  exit                         Exit configuration mode")
-	fmt.Println("  help, ?                      Show this help message")
+// dirty reports whether the candidate configuration has uncommitted changes
+func (cm *CommandManager) dirty() bool {
+	return !cm.configManager.GetConfig().Equal(cm.configManager.GetRunningConfig())
 }
 
 // prettyPrintConfig prints the configuration in a readable format
@@ -312,10 +476,36 @@ func (cm *CommandManager) prettyPrintConfig(cfg *config.Config) {
 	fmt.Println("Interfaces:")
 	for name, iface := range cfg.Interfaces {
 		fmt.Printf("  %s:\n", name)
-		fmt.Printf("    Address: %s\n", iface.Address)
+		if iface.Description != "" {
+			fmt.Printf("    Description: %s\n", iface.Description)
+		}
+		for _, addr := range iface.Addresses {
+			fmt.Printf("    Address: %s\n", addr)
+		}
+		if iface.IPv6.Autoconf {
+			fmt.Println("    IPv6 address: autoconf")
+		}
 		if iface.MAC != "" {
 			fmt.Printf("    MAC: %s\n", iface.MAC)
 		}
+		if iface.MTU != 0 {
+			fmt.Printf("    MTU: %d\n", iface.MTU)
+		}
+		if iface.Speed != "" {
+			fmt.Printf("    Speed: %s\n", iface.Speed)
+		}
+		if iface.Duplex != "" {
+			fmt.Printf("    Duplex: %s\n", iface.Duplex)
+		}
+		if len(iface.Offload) > 0 {
+			fmt.Printf("    Offload: %s\n", strings.Join(iface.Offload, " "))
+		}
+		if iface.Disable {
+			fmt.Println("    Disabled")
+		}
+		if iface.Virtual {
+			fmt.Println("    Virtual")
+		}
 	}
 	fmt.Println("DNS servers:")
 	for _, dns := range cfg.DNS {
@@ -326,33 +516,27 @@ func (cm *CommandManager) prettyPrintConfig(cfg *config.Config) {
 	}
 }
 
-// splitFields splits a line into fields by spaces
-func splitFields(s string) []string {
-	var res []string
-	field := ""
-	for i := 0; i < len(s); i++ {
-		if s[i] == ' ' {
-			if field != "" {
-				res = append(res, field)
-				field = ""
-			}
-		} else {
-			field += string(s[i])
-		}
-	}
-	if field != "" {
-		res = append(res, field)
+// printLexError prints a command line error with a marker under the offending column
+func printLexError(line string, err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	var lexErr *lexer.Error
+	if errors.As(err, &lexErr) {
+		fmt.Fprintf(os.Stderr, "  %s\n  %s^\n", line, strings.Repeat(" ", lexErr.Column-1))
 	}
-	return res
 }
 
 // Test Helper Methods
 
-// GetConfig returns the current configuration
+// GetConfig returns the candidate configuration
 func (cm *CommandManager) GetConfig() *config.Config {
 	return cm.configManager.GetConfig()
 }
 
+// GetRunningConfig returns the running configuration
+func (cm *CommandManager) GetRunningConfig() *config.Config {
+	return cm.configManager.GetRunningConfig()
+}
+
 // SetDNS sets the DNS servers
 func (cm *CommandManager) SetDNS(servers []string) {
 	cm.configManager.SetDNS(servers)
@@ -363,6 +547,41 @@ func (cm *CommandManager) SetDefaultRoute(route string) {
 	cm.configManager.SetDefaultRoute(route)
 }
 
+// CommandTree returns the command schema bound to this CommandManager
+func (cm *CommandManager) CommandTree() *schema.Node {
+	return cm.tree
+}
+
+// SetBackend sets the backend used to apply configuration to the system
+func (cm *CommandManager) SetBackend(backend system.Backend) {
+	cm.backend = backend
+}
+
+// SetDryRun makes commits print their plan instead of applying it
+func (cm *CommandManager) SetDryRun(dryRun bool) {
+	cm.dryRun = dryRun
+}
+
+// Exited reports whether the session was ended by exit
+func (cm *CommandManager) Exited() bool {
+	return cm.exited
+}
+
+// Prompt returns the prompt of the interactive mode
+func (cm *CommandManager) Prompt() string {
+	return cm.prompt()
+}
+
+// Completer returns the tab completer of the interactive mode
+func (cm *CommandManager) Completer() *completer.CLICompleter {
+	return cm.completer()
+}
+
+// EditPath returns the current configuration level
+func (cm *CommandManager) EditPath() []string {
+	return cm.editPath
+}
+
 // SetInterface sets interface configuration
 func (cm *CommandManager) SetInterface(name string, iface config.InterfaceConfig) {
 	cm.configManager.SetInterface(name, iface)
@@ -375,10 +594,16 @@ var rootCmd = &cobra.Command{
 	Short: "Configure network settings",
 	Long:  `A command line tool for configuring network settings.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		cm, err := NewCommandManager("boot.config.yaml", "running.config.yaml")
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+		cm := newCLICommandManager(cmd)
+
+		file, _ := cmd.Flags().GetString("file")
+		keepGoing, _ := cmd.Flags().GetBool("keep-going")
+		if file != "" || !readline.IsTerminal(int(os.Stdin.Fd())) {
+			if err := cm.runBatchInput(file, keepGoing); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
 		}
 		if err := cm.Execute(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -387,6 +612,34 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+func init() {
+	rootCmd.PersistentFlags().Bool("dry-run", false, "print the system operations of commits instead of applying them")
+	rootCmd.Flags().StringP("file", "f", "", "run the commands in a file instead of the interactive mode (- for stdin)")
+	rootCmd.Flags().Bool("keep-going", false, "continue running commands from a file or stdin after an error")
+}
+
+// Configuration files used by the CLI, relative to the working directory
+const (
+	BootConfigFile    = "boot.config.yaml"
+	RunningConfigFile = "running.config.yaml"
+)
+
+// newCLICommandManager creates the CommandManager for the configuration files in
+// the working directory, set up from the command line flags. It exits on error.
+func newCLICommandManager(cmd *cobra.Command) *CommandManager {
+	cm, err := NewCommandManager(BootConfigFile, RunningConfigFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	cm.SetDryRun(dryRun)
+	cm.rollbackTimer = func(id string) error {
+		return startRollbackTimer(BootConfigFile, RunningConfigFile, id)
+	}
+	return cm
+}
+
 // Execute executes the root command
 func Execute() error {
 	return rootCmd.Execute()
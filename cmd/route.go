@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"configure/internal/config"
+	"configure/internal/plan"
+	"configure/internal/schema"
+	"configure/internal/validator"
+)
+
+// Static route commands: set and delete take the words after
+// "protocols static route", i.e. the prefix followed by
+// next-hop <ip>, interface <iface> or blackhole and an optional distance.
+
+// routeTargetNodes returns the next-hop, interface and blackhole nodes below a
+// route prefix. With del set they delete the target or its distance instead
+// of setting it.
+func routeTargetNodes(interfaceValue *schema.ValueType, run schema.Handler, del bool) []*schema.Node {
+	// handler passes the prefix, kind and target to run, followed by
+	// "distance" and its value for the distance node
+	handler := func(kind string, hasTarget, distance bool) schema.Handler {
+		return func(args []string) error {
+			n := 1
+			if hasTarget {
+				n = 2
+			}
+			fields := append([]string{args[0], kind}, args[1:n]...)
+			if distance {
+				fields = append(append(fields, "distance"), args[n:]...)
+			}
+			return run(fields)
+		}
+	}
+	distanceNode := func(kind string, hasTarget bool) *schema.Node {
+		if del {
+			return &schema.Node{Keyword: "distance", Help: "Restore the default distance", Run: handler(kind, hasTarget, true)}
+		}
+		return &schema.Node{Keyword: "distance", Help: "Administrative distance", Children: []*schema.Node{
+			{Value: distanceValue, Help: "Set distance", Run: handler(kind, hasTarget, true)},
+		}}
+	}
+	verb := "Set"
+	if del {
+		verb = "Delete"
+	}
+	return []*schema.Node{
+		{Keyword: "next-hop", Help: "Forward through a gateway", Children: []*schema.Node{
+			{Value: gatewayValue, Help: verb + " next-hop", Run: handler("next-hop", true, false), Children: []*schema.Node{
+				distanceNode("next-hop", true),
+			}},
+		}},
+		{Keyword: "interface", Help: "Forward out of a directly connected interface", Children: []*schema.Node{
+			{Value: interfaceValue, Help: verb + " interface route", Run: handler("interface", true, false), Children: []*schema.Node{
+				distanceNode("interface", true),
+			}},
+		}},
+		{Keyword: "blackhole", Help: verb + " blackhole route", Run: handler("blackhole", false, false), Children: []*schema.Node{
+			distanceNode("blackhole", false),
+		}},
+	}
+}
+
+// parseRouteFields splits the words of a static route command into the
+// prefix, target kind, target and remaining words
+func parseRouteFields(fields []string) (prefix, kind, target string, rest []string, err error) {
+	if len(fields) == 0 {
+		return "", "", "", nil, fmt.Errorf("missing route prefix")
+	}
+	prefix = fields[0]
+	if len(fields) == 1 {
+		return prefix, "", "", nil, nil
+	}
+	kind, rest = fields[1], fields[2:]
+	switch kind {
+	case "blackhole":
+	case "next-hop", "interface":
+		if len(rest) == 0 {
+			return "", "", "", nil, fmt.Errorf("missing %s", kind)
+		}
+		target, rest = rest[0], rest[1:]
+	default:
+		return "", "", "", nil, fmt.Errorf("unknown route target: %s", kind)
+	}
+	return prefix, kind, target, rest, nil
+}
+
+// routeTarget returns the target of a route, or nil if it is not configured
+func routeTarget(route config.StaticRoute, kind, target string) *config.RouteTarget {
+	var t config.RouteTarget
+	var ok bool
+	switch kind {
+	case "next-hop":
+		t, ok = route.NextHops[target]
+	case "interface":
+		t, ok = route.Interfaces[target]
+	default:
+		if route.Blackhole == nil {
+			return nil
+		}
+		t, ok = *route.Blackhole, true
+	}
+	if !ok {
+		return nil
+	}
+	return &t
+}
+
+// setRouteTarget sets or, if t is nil, removes the target of a route
+func setRouteTarget(route *config.StaticRoute, kind, target string, t *config.RouteTarget) {
+	targets := &route.NextHops
+	switch kind {
+	case "blackhole":
+		route.Blackhole = t
+		return
+	case "interface":
+		targets = &route.Interfaces
+	}
+	if t == nil {
+		delete(*targets, target)
+		return
+	}
+	if *targets == nil {
+		*targets = make(map[string]config.RouteTarget)
+	}
+	(*targets)[target] = *t
+}
+
+// HandleSetStaticRoute adds a target to a static route or sets its distance
+func (cm *CommandManager) HandleSetStaticRoute(fields []string) error {
+	prefix, kind, target, rest, err := parseRouteFields(fields)
+	if err != nil {
+		return err
+	}
+	if kind == "" {
+		return fmt.Errorf("missing next-hop, interface or blackhole for route %s", prefix)
+	}
+	if err := validator.ValidatePrefix(prefix); err != nil {
+		return fmt.Errorf("invalid route prefix: %w", err)
+	}
+	switch kind {
+	case "next-hop":
+		if err := validator.ValidateGateway(target); err != nil {
+			return fmt.Errorf("invalid next-hop: %w", err)
+		}
+	case "interface":
+		if !cm.configManager.GetConfig().Interfaces[target].Virtual {
+			if err := cm.checkInterfaceExists(target); err != nil {
+				return err
+			}
+		}
+	}
+	distance := 0
+	switch {
+	case len(rest) == 2 && rest[0] == "distance":
+		if err := validator.ValidateDistance(rest[1]); err != nil {
+			return fmt.Errorf("invalid distance: %w", err)
+		}
+		distance, _ = strconv.Atoi(rest[1])
+	case len(rest) != 0:
+		return fmt.Errorf("too many arguments for route %s", prefix)
+	}
+
+	route, _ := cm.configManager.StaticRoute(prefix)
+	t := routeTarget(route, kind, target)
+	if t == nil {
+		t = &config.RouteTarget{}
+	}
+	if distance != 0 {
+		t.Distance = distance
+	}
+	setRouteTarget(&route, kind, target, t)
+	cm.configManager.SetStaticRoute(prefix, route)
+	fmt.Printf("Set static route %s\n", strings.Join(fields, " "))
+	return nil
+}
+
+// HandleDeleteStaticRoute removes a static route, one of its targets or the
+// distance of a target
+func (cm *CommandManager) HandleDeleteStaticRoute(fields []string) error {
+	prefix, kind, target, rest, err := parseRouteFields(fields)
+	if err != nil {
+		return err
+	}
+	if kind == "" {
+		if err := cm.configManager.DeleteStaticRoute(prefix); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted static route %s\n", prefix)
+		return nil
+	}
+	route, ok := cm.configManager.StaticRoute(prefix)
+	if !ok {
+		return fmt.Errorf("static route %s is not configured", prefix)
+	}
+	t := routeTarget(route, kind, target)
+	if t == nil {
+		return fmt.Errorf("static route %s has no %s configured", prefix, strings.TrimSpace(kind+" "+target))
+	}
+	switch {
+	case len(rest) == 1 && rest[0] == "distance":
+		if t.Distance == 0 {
+			return fmt.Errorf("static route %s %s has no distance configured", prefix, strings.TrimSpace(kind+" "+target))
+		}
+		t.Distance = 0
+	case len(rest) != 0:
+		return fmt.Errorf("too many arguments for route %s", prefix)
+	default:
+		t = nil
+	}
+	setRouteTarget(&route, kind, target, t)
+	cm.configManager.SetStaticRoute(prefix, route)
+	fmt.Printf("Deleted static route %s\n", strings.Join(fields, " "))
+	return nil
+}
+
+// handleShowConfiguredRoutes displays the routes of the running configuration
+// in the form commit installs them. It does not query the routing table, so
+// routes changed outside of configure are not shown.
+func (cm *CommandManager) handleShowConfiguredRoutes() {
+	cfg := cm.configManager.GetRunningConfig()
+	routes := plan.StaticRoutes(cfg)
+	if cfg.DefaultRoute == "" && len(routes) == 0 {
+		fmt.Println("No routes configured")
+		return
+	}
+	fmt.Println("Configured routes (running configuration):")
+	if cfg.DefaultRoute != "" {
+		fmt.Printf("default via %s\n", cfg.DefaultRoute)
+	}
+	for _, r := range routes {
+		fmt.Println(r.String())
+	}
+}
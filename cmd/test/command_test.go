@@ -1,20 +1,20 @@
 package test
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
-	"configure/cmd"
 	"configure/internal/config"
 )
 
 // TestHandleSetDNS tests the handleSetDNS function.
 func TestHandleSetDNS(t *testing.T) {
 	env := SetupTestEnv(t)
-	cm, err := cmd.NewCommandManager(env.BootConfig, env.RunningConfig)
-	if err != nil {
-		t.Fatalf("Failed to create command manager: %v", err)
-	}
+	cm, _ := newCommandManager(t, env)
 
 	// Test case: Valid DNS address
 	dnsAddr := "8.8.8.8"
@@ -33,10 +33,7 @@ func TestHandleSetDNS(t *testing.T) {
 // TestHandleAddDNS tests the handleAddDNS function.
 func TestHandleAddDNS(t *testing.T) {
 	env := SetupTestEnv(t)
-	cm, err := cmd.NewCommandManager(env.BootConfig, env.RunningConfig)
-	if err != nil {
-		t.Fatalf("Failed to create command manager: %v", err)
-	}
+	cm, _ := newCommandManager(t, env)
 
 	// Test case: Valid DNS address
 	dnsAddr := "1.1.1.1"
@@ -60,13 +57,11 @@ func TestHandleAddDNS(t *testing.T) {
 // TestHandleSetInterface tests the handleSetInterface function.
 func TestHandleSetInterface(t *testing.T) {
 	env := SetupTestEnv(t)
-	cm, err := cmd.NewCommandManager(env.BootConfig, env.RunningConfig)
-	if err != nil {
-		t.Fatalf("Failed to create command manager: %v", err)
-	}
+	cm, recorder := newCommandManager(t, env)
+	recorder.LinkNames = []string{"eth0"}
 
 	// Test case: Valid interface parameters
-	fields := []string{"eth0", "address", "192.168.1.1"}
+	fields := []string{"eth0", "address", "192.168.1.1/24"}
 	if err := cm.HandleSetInterface(fields); err != nil {
 		t.Errorf("HandleSetInterface failed: %v", err)
 	}
@@ -75,18 +70,34 @@ func TestHandleSetInterface(t *testing.T) {
 	iface, exists := cfg.Interfaces["eth0"]
 	if !exists {
 		t.Error("Expected interface eth0 to exist")
-	} else if iface.Address != "192.168.1.1" {
-		t.Errorf("Expected interface address to be set to 192.168.1.1, got %s", iface.Address)
+	} else if !iface.HasAddress("192.168.1.1/24") {
+		t.Errorf("Expected interface address to be set to 192.168.1.1/24, got %s", iface.Addresses)
+	}
+
+	// Test case: Interface missing from the system is refused
+	if err := cm.HandleSetInterface([]string{"wg0", "address", "10.0.0.1/24"}); err == nil {
+		t.Error("Expected error for unknown interface wg0, got nil")
+	}
+	if _, exists := cm.GetConfig().Interfaces["wg0"]; exists {
+		t.Error("Expected unknown interface wg0 not to be configured")
+	}
+
+	// Test case: Virtual interfaces may be configured before they exist
+	if err := cm.HandleCommand([]string{"set", "interfaces", "wg0", "virtual"}); err != nil {
+		t.Errorf("set interfaces wg0 virtual failed: %v", err)
+	}
+	if err := cm.HandleSetInterface([]string{"wg0", "address", "10.0.0.1/24"}); err != nil {
+		t.Errorf("HandleSetInterface on virtual interface failed: %v", err)
+	}
+	if iface := cm.GetConfig().Interfaces["wg0"]; !iface.Virtual || !iface.HasAddress("10.0.0.1/24") {
+		t.Errorf("Expected virtual wg0 with address 10.0.0.1/24, got %+v", iface)
 	}
 }
 
 // TestHandleSetDefaultRoute tests the handleSetDefaultRoute function.
 func TestHandleSetDefaultRoute(t *testing.T) {
 	env := SetupTestEnv(t)
-	cm, err := cmd.NewCommandManager(env.BootConfig, env.RunningConfig)
-	if err != nil {
-		t.Fatalf("Failed to create command manager: %v", err)
-	}
+	cm, _ := newCommandManager(t, env)
 
 	// Test case: Valid default route
 	fields := []string{"192.168.1.1"}
@@ -103,10 +114,7 @@ func TestHandleSetDefaultRoute(t *testing.T) {
 // TestHandleSave tests the handleSave function.
 func TestHandleSave(t *testing.T) {
 	env := SetupTestEnv(t)
-	cm, err := cmd.NewCommandManager(env.BootConfig, env.RunningConfig)
-	if err != nil {
-		t.Fatalf("Failed to create command manager: %v", err)
-	}
+	cm, _ := newCommandManager(t, env)
 
 	// Test case: Save configuration
 	if err := cm.HandleSave(); err != nil {
@@ -130,21 +138,702 @@ func TestHandleSave(t *testing.T) {
 // TestHandleCommit tests the handleCommit function.
 func TestHandleCommit(t *testing.T) {
 	env := SetupTestEnv(t)
-	cm, err := cmd.NewCommandManager(env.BootConfig, env.RunningConfig)
-	if err != nil {
-		t.Fatalf("Failed to create command manager: %v", err)
-	}
+	cm, recorder := newCommandManager(t, env)
 
 	// Set up test configuration
 	cm.SetDNS([]string{"8.8.8.8"})
 	cm.SetDefaultRoute("192.168.1.1")
 	cm.SetInterface("eth0", config.InterfaceConfig{
-		Address: "192.168.1.100",
-		MAC:     "00:11:22:33:44:55",
+		Addresses: config.AddressList{"192.168.1.100/24"},
+		MAC:       "00:11:22:33:44:55",
 	})
 
 	// Test case: Commit configuration
 	if err := cm.HandleCommit(); err != nil {
 		t.Errorf("HandleCommit failed: %v", err)
 	}
+	expectOps(t, recorder, []string{
+		"link set eth0 address 00:11:22:33:44:55",
+		"address add 192.168.1.100/24 dev eth0",
+		"write resolv.conf 8.8.8.8",
+		"restart resolvconf.service",
+		"restart systemd-resolved.service",
+		"route replace default via 192.168.1.1",
+	})
+
+	// Test case: Running config follows the committed candidate
+	if cfg := cm.GetRunningConfig(); cfg.DefaultRoute != "192.168.1.1" {
+		t.Errorf("Expected running default route 192.168.1.1, got %s", cfg.DefaultRoute)
+	}
+}
+
+// TestHandleDelete tests the delete commands.
+func TestHandleDelete(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, _ := newCommandManager(t, env)
+
+	cm.SetDNS([]string{"8.8.8.8", "1.1.1.1"})
+	cm.SetDefaultRoute("192.168.1.1")
+	cm.SetInterface("eth0", config.InterfaceConfig{
+		Addresses: config.AddressList{"192.168.1.100/24"},
+		MAC:       "00:11:22:33:44:55",
+	})
+	cm.SetInterface("eth1", config.InterfaceConfig{Addresses: config.AddressList{"10.0.0.1/24"}})
+
+	// Test case: Delete a single DNS server
+	if err := cm.HandleCommand([]string{"delete", "dns", "8.8.8.8"}); err != nil {
+		t.Errorf("delete dns failed: %v", err)
+	}
+	if cfg := cm.GetConfig(); len(cfg.DNS) != 1 || cfg.DNS[0] != "1.1.1.1" {
+		t.Errorf("Expected DNS [1.1.1.1], got %v", cfg.DNS)
+	}
+
+	// Test case: Delete a DNS server that is not configured
+	if err := cm.HandleCommand([]string{"delete", "dns", "8.8.8.8"}); err == nil {
+		t.Error("Expected error when deleting unconfigured DNS server, got nil")
+	}
+
+	// Test case: Delete an interface parameter
+	if err := cm.HandleCommand([]string{"delete", "interfaces", "eth0", "mac"}); err != nil {
+		t.Errorf("delete interfaces eth0 mac failed: %v", err)
+	}
+	if iface := cm.GetConfig().Interfaces["eth0"]; iface.MAC != "" || !iface.HasAddress("192.168.1.100/24") {
+		t.Errorf("Expected only MAC to be deleted, got %+v", iface)
+	}
+
+	// Test case: Delete a whole interface
+	if err := cm.HandleCommand([]string{"delete", "interfaces", "eth1"}); err != nil {
+		t.Errorf("delete interfaces eth1 failed: %v", err)
+	}
+	if _, exists := cm.GetConfig().Interfaces["eth1"]; exists {
+		t.Error("Expected interface eth1 to be deleted")
+	}
+
+	// Test case: Deleting the last parameter removes the interface
+	if err := cm.HandleCommand([]string{"delete", "interfaces", "eth0", "address"}); err != nil {
+		t.Errorf("delete interfaces eth0 address failed: %v", err)
+	}
+	if _, exists := cm.GetConfig().Interfaces["eth0"]; exists {
+		t.Error("Expected interface eth0 without parameters to be removed")
+	}
+	cm.SetInterface("eth2", config.InterfaceConfig{IPv6: config.IPv6Config{Autoconf: true}})
+	if err := cm.HandleCommand([]string{"delete", "interfaces", "eth2", "ipv6", "address", "autoconf"}); err != nil {
+		t.Errorf("delete interfaces eth2 ipv6 address autoconf failed: %v", err)
+	}
+	if _, exists := cm.GetConfig().Interfaces["eth2"]; exists {
+		t.Error("Expected interface eth2 without parameters to be removed")
+	}
+
+	// Test case: Delete the default route
+	if err := cm.HandleCommand([]string{"delete", "ip", "route", "default"}); err != nil {
+		t.Errorf("delete ip route default failed: %v", err)
+	}
+	if cm.GetConfig().DefaultRoute != "" {
+		t.Errorf("Expected default route to be deleted, got %s", cm.GetConfig().DefaultRoute)
+	}
+
+	// Test case: Delete all DNS servers
+	if err := cm.HandleCommand([]string{"delete", "dns"}); err != nil {
+		t.Errorf("delete dns failed: %v", err)
+	}
+	if len(cm.GetConfig().DNS) != 0 {
+		t.Errorf("Expected DNS to be empty, got %v", cm.GetConfig().DNS)
+	}
+}
+
+// TestHandleRollback tests loading an archived revision into the candidate.
+func TestHandleRollback(t *testing.T) {
+	env := SetupTestEnv(t)
+	for _, dns := range []string{"8.8.8.8", "1.1.1.1"} {
+		env.ConfigManager.SetDNS([]string{dns})
+		if err := env.ConfigManager.Commit(); err != nil {
+			t.Fatalf("Failed to commit config: %v", err)
+		}
+		if _, err := env.ConfigManager.Archive("tester", ""); err != nil {
+			t.Fatalf("Failed to archive config: %v", err)
+		}
+	}
+	cm, _ := newCommandManager(t, env)
+
+	// Test case: Roll back to the previous revision
+	if err := cm.HandleCommand([]string{"rollback", "1"}); err != nil {
+		t.Errorf("rollback 1 failed: %v", err)
+	}
+	if cfg := cm.GetConfig(); len(cfg.DNS) != 1 || cfg.DNS[0] != "8.8.8.8" {
+		t.Errorf("Expected candidate DNS [8.8.8.8], got %v", cfg.DNS)
+	}
+	if cfg := cm.GetRunningConfig(); len(cfg.DNS) != 1 || cfg.DNS[0] != "1.1.1.1" {
+		t.Errorf("Expected running DNS to stay [1.1.1.1], got %v", cfg.DNS)
+	}
+
+	// Test case: Revision that does not exist
+	if err := cm.HandleCommand([]string{"rollback", "5"}); err == nil {
+		t.Error("Expected error for missing revision, got nil")
+	}
+}
+
+// TestHandleCommitComment tests archiving a commit with its comment.
+func TestHandleCommitComment(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, _ := newCommandManager(t, env)
+
+	// Test case: Quotes inside the comment are kept
+	cm.SetDNS([]string{"8.8.8.8"})
+	if err := cm.HandleCommand([]string{"commit", "comment", `say "hi"`}); err != nil {
+		t.Fatalf("commit comment failed: %v", err)
+	}
+	rev, err := env.ConfigManager.Revision(0)
+	if err != nil {
+		t.Fatalf("Failed to read revision 0: %v", err)
+	}
+	if rev.Comment != `say "hi"` {
+		t.Errorf("Expected comment %q, got %q", `say "hi"`, rev.Comment)
+	}
+}
+
+// TestCommitConfirmDryRun tests that commit-confirm in dry-run mode only prints the plan.
+func TestCommitConfirmDryRun(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, recorder := newCommandManager(t, env)
+	cm.SetDryRun(true)
+
+	// Test case: No system changes and no pending rollback
+	cm.SetDNS([]string{"8.8.8.8"})
+	if err := cm.HandleCommand([]string{"commit-confirm", "5"}); err != nil {
+		t.Fatalf("commit-confirm failed: %v", err)
+	}
+	expectOps(t, recorder, nil)
+	if p, err := env.ConfigManager.PendingRollback(); err != nil || p != nil {
+		t.Errorf("Expected no pending rollback in dry-run mode, got %v, %v", p, err)
+	}
+	if len(cm.GetRunningConfig().DNS) != 0 {
+		t.Errorf("Expected running DNS to stay empty, got %v", cm.GetRunningConfig().DNS)
+	}
+}
+
+// TestHandleCommitConfirm tests confirming and automatically rolling back a commit-confirm.
+func TestHandleCommitConfirm(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, recorder := newCommandManager(t, env)
+
+	// Test case: Confirmed commit stays in place
+	cm.SetDNS([]string{"8.8.8.8"})
+	if err := cm.HandleCommand([]string{"commit-confirm", "5"}); err != nil {
+		t.Fatalf("commit-confirm failed: %v", err)
+	}
+	if err := cm.HandleCommand([]string{"confirm"}); err != nil {
+		t.Fatalf("confirm failed: %v", err)
+	}
+	if p, _ := env.ConfigManager.PendingRollback(); p != nil {
+		t.Errorf("Expected no pending rollback after confirm, got %s", p.ID)
+	}
+	if err := cm.HandleCommand([]string{"confirm"}); err == nil {
+		t.Error("Expected error when nothing is pending, got nil")
+	}
+
+	// Test case: Nothing to roll back without changes
+	if err := cm.HandleCommand([]string{"commit-confirm", "5"}); err != nil {
+		t.Fatalf("commit-confirm failed: %v", err)
+	}
+	if p, err := env.ConfigManager.PendingRollback(); err != nil || p != nil {
+		t.Errorf("Expected no pending rollback without changes, got %v, %v", p, err)
+	}
+
+	// Test case: Expired commit is rolled back on the next command
+	cm.SetDNS([]string{"1.1.1.1"})
+	if err := cm.HandleCommand([]string{"commit-confirm", "5"}); err != nil {
+		t.Fatalf("commit-confirm failed: %v", err)
+	}
+	p, err := env.ConfigManager.PendingRollback()
+	if err != nil || p == nil {
+		t.Fatalf("Expected pending rollback, got %v, %v", p, err)
+	}
+	p.Deadline = time.Now().Add(-time.Second)
+	if err := env.ConfigManager.SetPendingRollback(p); err != nil {
+		t.Fatalf("Failed to expire pending rollback: %v", err)
+	}
+
+	// Test case: A failed rollback keeps the record so it is retried
+	recorder.Errors["write resolv.conf 8.8.8.8"] = errors.New("disk full")
+	if err := cm.HandleCommand([]string{"show", "dns"}); err == nil {
+		t.Fatal("Expected error when the rollback fails, got nil")
+	}
+	if p, err := env.ConfigManager.PendingRollback(); err != nil || p == nil {
+		t.Fatalf("Expected pending rollback to be kept after a failed rollback, got %v, %v", p, err)
+	}
+	if cfg := cm.GetRunningConfig(); len(cfg.DNS) != 1 || cfg.DNS[0] != "1.1.1.1" {
+		t.Errorf("Expected running DNS to stay [1.1.1.1] after a failed rollback, got %v", cfg.DNS)
+	}
+	delete(recorder.Errors, "write resolv.conf 8.8.8.8")
+
+	recorder.Ops = nil
+	if err := cm.HandleCommand([]string{"show", "dns"}); err != nil {
+		t.Fatalf("show dns failed: %v", err)
+	}
+	expectOps(t, recorder, []string{
+		"write resolv.conf 8.8.8.8",
+		"restart resolvconf.service",
+		"restart systemd-resolved.service",
+	})
+	if cfg := cm.GetRunningConfig(); len(cfg.DNS) != 1 || cfg.DNS[0] != "8.8.8.8" {
+		t.Errorf("Expected running DNS to be rolled back to [8.8.8.8], got %v", cfg.DNS)
+	}
+	if cfg := cm.GetConfig(); len(cfg.DNS) != 1 || cfg.DNS[0] != "8.8.8.8" {
+		t.Errorf("Expected candidate DNS to follow the rollback, got %v", cfg.DNS)
+	}
+
+	// Test case: Rollback by another process is noticed by the committing session
+	cm.SetDNS([]string{"9.9.9.9"})
+	if err := cm.HandleCommand([]string{"commit-confirm", "5"}); err != nil {
+		t.Fatalf("commit-confirm failed: %v", err)
+	}
+	p, err = env.ConfigManager.PendingRollback()
+	if err != nil || p == nil {
+		t.Fatalf("Expected pending rollback, got %v, %v", p, err)
+	}
+	p.Deadline = time.Now().Add(-time.Second)
+	if err := env.ConfigManager.SetPendingRollback(p); err != nil {
+		t.Fatalf("Failed to expire pending rollback: %v", err)
+	}
+	other, _ := newCommandManager(t, env)
+	if err := other.HandleCommand([]string{"show", "dns"}); err != nil {
+		t.Fatalf("show dns failed: %v", err)
+	}
+	if err := cm.HandleCommand([]string{"show", "dns"}); err != nil {
+		t.Fatalf("show dns failed: %v", err)
+	}
+	if cfg := cm.GetRunningConfig(); len(cfg.DNS) != 1 || cfg.DNS[0] != "8.8.8.8" {
+		t.Errorf("Expected running DNS to be reloaded as [8.8.8.8], got %v", cfg.DNS)
+	}
+	if cfg := cm.GetConfig(); len(cfg.DNS) != 1 || cfg.DNS[0] != "8.8.8.8" {
+		t.Errorf("Expected unedited candidate DNS to follow the rollback, got %v", cfg.DNS)
+	}
+}
+
+// TestEditNavigation tests edit, up, top and exit with commands relative to the edit level.
+func TestEditNavigation(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, recorder := newCommandManager(t, env)
+	recorder.LinkNames = []string{"eth0"}
+
+	run := func(line string) {
+		t.Helper()
+		if err := cm.HandleCommand(strings.Fields(line)); err != nil {
+			t.Fatalf("%s failed: %v", line, err)
+		}
+	}
+	path := func() string { return strings.Join(cm.EditPath(), " ") }
+
+	// Test case: Commands are relative to the edit level
+	run("edit interfaces")
+	run("edit eth0")
+	if path() != "interfaces eth0" {
+		t.Errorf("Expected edit level 'interfaces eth0', got %q", path())
+	}
+	run("set address 192.168.1.10/24")
+	if iface := cm.GetConfig().Interfaces["eth0"]; !iface.HasAddress("192.168.1.10/24") {
+		t.Errorf("Expected eth0 address 192.168.1.10/24, got %s", iface.Addresses)
+	}
+
+	// Test case: Completion is scoped to the edit level
+	if got := complete(cm.Completer(), "set "); strings.Join(got, ",") != "address,description,disable,duplex,ipv6,mac,mtu,offload,speed,virtual" {
+		t.Errorf("Expected completions address,description,disable,duplex,ipv6,mac,mtu,offload,speed,virtual at edit level, got %v", got)
+	}
+
+	// Test case: Other commands are not relative to the edit level
+	run("show dns")
+	if err := cm.HandleCommand([]string{"set", "dns", "8.8.8.8"}); err == nil {
+		t.Error("Expected set dns to be relative to the edit level, got nil error")
+	}
+
+	// Test case: up and exit leave one level, top leaves all
+	run("up")
+	if path() != "interfaces" {
+		t.Errorf("Expected edit level 'interfaces' after up, got %q", path())
+	}
+	run("exit")
+	if path() != "" {
+		t.Errorf("Expected top level after exit, got %q", path())
+	}
+	run("edit ip route default")
+	run("top")
+	if path() != "" {
+		t.Errorf("Expected top level after top, got %q", path())
+	}
+
+	// Test case: Unknown levels and leaf values cannot be edited
+	for _, line := range []string{"edit bogus", "edit dns 8.8.8.8"} {
+		if err := cm.HandleCommand(strings.Fields(line)); err == nil {
+			t.Errorf("Expected error for %q, got nil", line)
+		}
+	}
+}
+
+// TestRunBatch tests running commands from a script.
+func TestRunBatch(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, _ := newCommandManager(t, env)
+
+	// Test case: Comments and empty lines are skipped
+	script := `# provisioning
+delete dns
+
+set dns 1.1.1.1   # primary
+add dns 9.9.9.9
+set ip route default via 10.0.0.1
+commit
+`
+	if err := cm.RunBatch(strings.NewReader(script), false); err != nil {
+		t.Fatalf("RunBatch failed: %v", err)
+	}
+	if running := cm.GetRunningConfig(); strings.Join(running.DNS, ",") != "1.1.1.1,9.9.9.9" || running.DefaultRoute != "10.0.0.1" {
+		t.Errorf("Expected committed DNS 1.1.1.1,9.9.9.9 and route 10.0.0.1, got %v and %s", running.DNS, running.DefaultRoute)
+	}
+
+	// Test case: The first error stops the script
+	script = "set dns bogus\nset ip route default via 10.0.0.2\n"
+	if err := cm.RunBatch(strings.NewReader(script), false); err == nil {
+		t.Error("Expected error for failing script, got nil")
+	}
+	if cm.GetConfig().DefaultRoute != "10.0.0.1" {
+		t.Errorf("Expected commands after the error to be skipped, got route %s", cm.GetConfig().DefaultRoute)
+	}
+
+	// Test case: keep-going runs the remaining commands but still fails
+	if err := cm.RunBatch(strings.NewReader(script), true); err == nil {
+		t.Error("Expected error for failing script with keep-going, got nil")
+	}
+	if cm.GetConfig().DefaultRoute != "10.0.0.2" {
+		t.Errorf("Expected commands after the error to run with keep-going, got route %s", cm.GetConfig().DefaultRoute)
+	}
+
+	// Test case: exit refuses to leave with uncommitted changes
+	if err := cm.RunBatch(strings.NewReader("exit\n"), false); err == nil {
+		t.Error("Expected exit to fail with uncommitted changes, got nil")
+	}
+
+	// Test case: exit discard ends the script
+	script = "exit discard\nset ip route default via 10.0.0.3\n"
+	if err := cm.RunBatch(strings.NewReader(script), false); err != nil {
+		t.Errorf("RunBatch with exit discard failed: %v", err)
+	}
+	if !cm.Exited() || cm.GetConfig().DefaultRoute != "10.0.0.1" {
+		t.Errorf("Expected session to end with changes discarded, got route %s", cm.GetConfig().DefaultRoute)
+	}
+}
+
+// TestOneShotCommands tests that changes made by one command run are committed by a later one.
+func TestOneShotCommands(t *testing.T) {
+	env := SetupTestEnv(t)
+	oneShot := func(fields ...string) error {
+		t.Helper()
+		cm, _ := newCommandManager(t, env)
+		return cm.HandleOneShot(fields)
+	}
+	run := func(fields ...string) {
+		t.Helper()
+		if err := oneShot(fields...); err != nil {
+			t.Fatalf("%s failed: %v", strings.Join(fields, " "), err)
+		}
+	}
+
+	run("set", "ip", "route", "default", "via", "10.0.0.1")
+
+	// Test case: Interactive sessions do not see one-shot changes
+	cm, _ := newCommandManager(t, env)
+	if cm.GetConfig().DefaultRoute != "" {
+		t.Errorf("Expected interactive candidate default route to be empty, got %s", cm.GetConfig().DefaultRoute)
+	}
+
+	run("commit")
+	cm, _ = newCommandManager(t, env)
+	if cm.GetRunningConfig().DefaultRoute != "10.0.0.1" {
+		t.Errorf("Expected running default route 10.0.0.1, got %s", cm.GetRunningConfig().DefaultRoute)
+	}
+
+	// Test case: Changes made before another session committed are refused
+	run("set", "dns", "8.8.8.8")
+	cm.SetDefaultRoute("10.0.0.2")
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+	if err := oneShot("commit"); !errors.Is(err, config.ErrStaleCandidate) {
+		t.Errorf("Expected ErrStaleCandidate, got %v", err)
+	}
+	run("discard")
+	run("commit")
+	if cm, _ := newCommandManager(t, env); cm.GetRunningConfig().DefaultRoute != "10.0.0.2" {
+		t.Errorf("Expected running default route to stay 10.0.0.2, got %s", cm.GetRunningConfig().DefaultRoute)
+	}
+}
+
+// TestLoadCommands tests that exported configuration commands recreate the configuration.
+func TestLoadCommands(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, recorder := newCommandManager(t, env)
+	recorder.LinkNames = []string{"eth0"}
+
+	for _, line := range []string{
+		"set hostname edge-1",
+		"set interfaces eth0 address 192.168.1.10/24",
+		"set interfaces eth0 mac 00:11:22:33:44:55",
+		"set interfaces wg0 virtual",
+		"set interfaces wg0 address 10.0.0.1/24",
+		"delete dns",
+		"add dns 9.9.9.9",
+		"add dns 1.1.1.1",
+		"set ip route default via 192.168.1.1",
+		"set protocols static route 10.0.0.0/8 next-hop 192.168.1.2 distance 5",
+		"set protocols static route 10.0.0.0/8 blackhole distance 200",
+		"set protocols static route 2001:db8:1::/48 interface eth0",
+		"set system commit-revisions 20",
+	} {
+		if err := cm.HandleCommand(strings.Fields(line)); err != nil {
+			t.Fatalf("%s failed: %v", line, err)
+		}
+	}
+	expected := cm.GetConfig().Clone()
+
+	// Test case: Export then load yields an identical configuration
+	file := filepath.Join(t.TempDir(), "config.cmds")
+	if err := os.WriteFile(file, []byte(strings.Join(config.Commands(expected), "\n")+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write command file: %v", err)
+	}
+	if err := cm.HandleCommand([]string{"delete", "interfaces", "wg0"}); err != nil {
+		t.Fatalf("delete interfaces wg0 failed: %v", err)
+	}
+	if err := cm.HandleCommand([]string{"load", "commands", file}); err != nil {
+		t.Fatalf("load commands failed: %v", err)
+	}
+	if !cm.GetConfig().Equal(expected) {
+		t.Errorf("Expected loaded configuration to equal the exported one, got %+v", cm.GetConfig())
+	}
+	if got := config.Commands(cm.GetConfig()); strings.Join(got, "\n") != strings.Join(config.Commands(expected), "\n") {
+		t.Errorf("Expected stable commands, got %v", got)
+	}
+
+	// Test case: A failing line leaves the candidate unchanged
+	if err := os.WriteFile(file, []byte("set hostname other\nset dns bogus\n"), 0644); err != nil {
+		t.Fatalf("Failed to write command file: %v", err)
+	}
+	if err := cm.HandleCommand([]string{"load", "commands", file}); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected error at line 2, got %v", err)
+	}
+	if cm.GetConfig().Hostname != "edge-1" {
+		t.Errorf("Expected candidate to be unchanged after failed load, got hostname %s", cm.GetConfig().Hostname)
+	}
+
+	// Test case: Only configuration commands can be loaded
+	if err := os.WriteFile(file, []byte("commit\n"), 0644); err != nil {
+		t.Fatalf("Failed to write command file: %v", err)
+	}
+	if err := cm.HandleCommand([]string{"load", "commands", file}); err == nil {
+		t.Error("Expected error for commit in command file, got nil")
+	}
+
+	// Test case: An interface without settings does not break the round trip
+	yamlFile := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(yamlFile, []byte("interfaces:\n  eth0:\n    address: 192.168.1.10/24\n  eth1: {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if err := cm.HandleCommand([]string{"load", yamlFile}); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	expected = cm.GetConfig().Clone()
+	if err := os.WriteFile(file, []byte(strings.Join(config.Commands(expected), "\n")+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write command file: %v", err)
+	}
+	if err := cm.HandleCommand([]string{"load", "commands", file}); err != nil {
+		t.Fatalf("load commands failed: %v", err)
+	}
+	if !cm.GetConfig().Equal(expected) {
+		t.Errorf("Expected loaded configuration to equal the exported one, got %+v", cm.GetConfig())
+	}
+}
+
+// TestLoadMerge tests loading and merging YAML configuration files into the candidate.
+func TestLoadMerge(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, recorder := newCommandManager(t, env)
+	recorder.LinkNames = []string{"eth0", "eth1"}
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+	running := cm.GetRunningConfig().Clone()
+
+	// Test case: load replaces the candidate and leaves it uncommitted
+	base := write("base.yaml", `hostname: edge-1
+interfaces:
+  eth0:
+    address: 192.168.1.10/24
+dns:
+  - 1.1.1.1
+`)
+	if err := cm.HandleCommand([]string{"load", base}); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	cfg := cm.GetConfig()
+	if cfg.Hostname != "edge-1" || !cfg.Interfaces["eth0"].HasAddress("192.168.1.10/24") || strings.Join(cfg.DNS, ",") != "1.1.1.1" || cfg.DefaultRoute != "" {
+		t.Errorf("Unexpected candidate after load: %+v", cfg)
+	}
+	if !cm.GetRunningConfig().Equal(running) {
+		t.Error("Expected running configuration to be unchanged by load")
+	}
+
+	// Test case: merge overlays settings, merges interfaces and unions DNS servers
+	overlay := write("overlay.yaml", `interfaces:
+  eth0:
+    mac: 00:11:22:33:44:55
+  eth1:
+    address: 10.0.0.1/24
+dns:
+  - 9.9.9.9
+  - 1.1.1.1
+default_route: 192.168.1.1
+`)
+	if err := cm.HandleCommand([]string{"merge", overlay}); err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	cfg = cm.GetConfig()
+	if cfg.Hostname != "edge-1" || cfg.DefaultRoute != "192.168.1.1" {
+		t.Errorf("Expected hostname edge-1 and default route 192.168.1.1, got %s and %s", cfg.Hostname, cfg.DefaultRoute)
+	}
+	if eth0 := cfg.Interfaces["eth0"]; !eth0.HasAddress("192.168.1.10/24") || eth0.MAC != "00:11:22:33:44:55" {
+		t.Errorf("Expected eth0 address and MAC to be merged, got %+v", eth0)
+	}
+	if !cfg.Interfaces["eth1"].HasAddress("10.0.0.1/24") {
+		t.Errorf("Expected eth1 to be added, got %+v", cfg.Interfaces["eth1"])
+	}
+	if strings.Join(cfg.DNS, ",") != "1.1.1.1,9.9.9.9" {
+		t.Errorf("Expected DNS servers 1.1.1.1,9.9.9.9, got %v", cfg.DNS)
+	}
+
+	// Test case: Invalid files leave the candidate unchanged
+	expected := cm.GetConfig().Clone()
+	for name, content := range map[string]string{
+		"unknown.yaml":   "hostnam: typo\n",
+		"address.yaml":   "interfaces:\n  eth0:\n    address: 300.1.1.1/24\n",
+		"dns.yaml":       "dns:\n  - bogus\n",
+		"interface.yaml": "interfaces:\n  eth9:\n    address: 10.0.0.1/24\n",
+	} {
+		path := write(name, content)
+		for _, verb := range []string{"load", "merge"} {
+			if err := cm.HandleCommand([]string{verb, path}); err == nil {
+				t.Errorf("Expected error for %s %s, got nil", verb, name)
+			}
+		}
+	}
+	if !cm.GetConfig().Equal(expected) {
+		t.Errorf("Expected candidate to be unchanged by invalid files, got %+v", cm.GetConfig())
+	}
+}
+
+// TestCommitValidation tests that invalid configurations are refused by commit and validate.
+func TestCommitValidation(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, recorder := newCommandManager(t, env)
+
+	cm.SetInterface("eth0", config.InterfaceConfig{Addresses: config.AddressList{"192.168.1.10/24"}})
+	cm.SetDefaultRoute("10.0.0.1")
+	for _, command := range []string{"validate", "commit"} {
+		if err := cm.HandleCommand([]string{command}); err == nil || !strings.Contains(err.Error(), "ip route default via") {
+			t.Errorf("Expected %s to report the unreachable default route, got %v", command, err)
+		}
+	}
+	expectOps(t, recorder, nil)
+	if cm.GetRunningConfig().DefaultRoute != "" {
+		t.Errorf("Expected running configuration to be unchanged, got route %s", cm.GetRunningConfig().DefaultRoute)
+	}
+
+	cm.SetDefaultRoute("192.168.1.1")
+	if err := cm.HandleCommand([]string{"validate"}); err != nil {
+		t.Errorf("validate failed: %v", err)
+	}
+}
+
+// TestDiscardAndExit tests discard, the dirty prompt marker and exit protection.
+func TestDiscardAndExit(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, _ := newCommandManager(t, env)
+	run := func(fields ...string) error { return cm.HandleCommand(fields) }
+
+	// Test case: The prompt marks uncommitted changes until they are discarded
+	if cm.Prompt() != "(config)# " {
+		t.Errorf("Expected clean prompt, got %q", cm.Prompt())
+	}
+	if err := run("set", "ip", "route", "default", "via", "10.0.0.1"); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+	if cm.Prompt() != "(config*)# " {
+		t.Errorf("Expected dirty prompt, got %q", cm.Prompt())
+	}
+	if err := run("exit"); err == nil || cm.Exited() {
+		t.Error("Expected exit to refuse with uncommitted changes")
+	}
+	if err := run("discard"); err != nil {
+		t.Fatalf("discard failed: %v", err)
+	}
+	if cm.GetConfig().DefaultRoute != cm.GetRunningConfig().DefaultRoute || cm.Prompt() != "(config)# " {
+		t.Errorf("Expected candidate to be reverted to running, got route %s", cm.GetConfig().DefaultRoute)
+	}
+
+	// Test case: exit refuses to leave an unsaved running configuration
+	if err := run("set", "ip", "route", "default", "via", "10.0.0.1"); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+	if err := run("commit"); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if err := run("exit"); err == nil || cm.Exited() {
+		t.Error("Expected exit to refuse with unsaved changes")
+	}
+	if err := run("save"); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if err := run("exit"); err != nil || !cm.Exited() {
+		t.Errorf("Expected exit to succeed after save, got %v", err)
+	}
+}
+
+// TestEndOfInput tests that Ctrl-D warns about uncommitted changes and only
+// drops them when repeated right away.
+func TestEndOfInput(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, _ := newCommandManager(t, env)
+
+	// Test case: Ctrl-D is refused with uncommitted changes
+	if err := cm.HandleCommand([]string{"set", "hostname", "edge-1"}); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+	if err := cm.HandleEndOfInput(); err == nil || !strings.Contains(err.Error(), "uncommitted changes") || cm.Exited() {
+		t.Errorf("Expected Ctrl-D to refuse with uncommitted changes, got %v", err)
+	}
+
+	// Test case: A command in between requires the warning again
+	if err := cm.HandleCommand([]string{"show", "dns"}); err != nil {
+		t.Fatalf("show dns failed: %v", err)
+	}
+	if err := cm.HandleEndOfInput(); err == nil || cm.Exited() {
+		t.Errorf("Expected Ctrl-D to refuse again after a command, got %v", err)
+	}
+
+	// Test case: A second Ctrl-D discards the changes and exits
+	if err := cm.HandleEndOfInput(); err != nil || !cm.Exited() {
+		t.Errorf("Expected second Ctrl-D to exit, got %v", err)
+	}
+	if cm.GetConfig().Hostname != "test-router" {
+		t.Errorf("Expected uncommitted changes to be discarded, got hostname %s", cm.GetConfig().Hostname)
+	}
+
+	// Test case: Ctrl-D exits right away without changes
+	cm, _ = newCommandManager(t, env)
+	if err := cm.HandleEndOfInput(); err != nil || !cm.Exited() {
+		t.Errorf("Expected Ctrl-D to exit without changes, got %v", err)
+	}
 }
@@ -0,0 +1,130 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"configure/internal/completer"
+	"configure/internal/config"
+	"configure/internal/schema"
+)
+
+// complete runs the completer on a line with the cursor at its end
+func complete(c *completer.CLICompleter, line string) []string {
+	candidates, _ := c.Do([]rune(line), len([]rune(line)))
+	var res []string
+	for _, cand := range candidates {
+		res = append(res, line[strings.LastIndexAny(line, " \t")+1:]+strings.TrimSpace(string(cand)))
+	}
+	return res
+}
+
+// TestCompleter tests tab completion generated from the command schema.
+func TestCompleter(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, recorder := newCommandManager(t, env)
+	recorder.LinkNames = []string{"eth0", "ens3"}
+	cm.SetInterface("wg0", config.InterfaceConfig{Addresses: config.AddressList{"10.0.0.1/24"}, Virtual: true})
+	c := &completer.CLICompleter{Root: cm.CommandTree()}
+
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{"set i", []string{"interfaces", "ip"}},
+		{"set ip route ", []string{"default"}},
+		{"commit", []string{"commit", "commit-confirm"}},
+		{"commit ", []string{"comment", "dry-run"}},
+		{"delete interfaces eth0 ", []string{"address", "description", "disable", "duplex", "ipv6", "mac", "mtu", "offload", "speed", "virtual"}},
+		{"set interfaces ", []string{"ens3", "eth0", "wg0"}},
+		{"set interfaces e", []string{"ens3", "eth0"}},
+		{"show | ", []string{"compare"}},
+		{"set dns ", nil},
+		{"set\ti", []string{"interfaces", "ip"}},
+		{"set 'interfaces' e", []string{"ens3", "eth0"}},
+		{"set i # comment", nil},
+		{"bogus ", nil},
+	}
+	for _, tt := range tests {
+		got := complete(c, tt.line)
+		if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("Completions for %q: expected %v, got %v", tt.line, tt.expected, got)
+		}
+	}
+}
+
+// TestCommandTree tests that commands are parsed through the command schema.
+func TestCommandTree(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, _ := newCommandManager(t, env)
+
+	// Test case: Default route with gateway
+	if err := cm.HandleCommand([]string{"set", "ip", "route", "default", "via", "192.168.1.1"}); err != nil {
+		t.Errorf("set ip route default via failed: %v", err)
+	}
+	if cm.GetConfig().DefaultRoute != "192.168.1.1" {
+		t.Errorf("Expected default route 192.168.1.1, got %s", cm.GetConfig().DefaultRoute)
+	}
+
+	// Test case: Each field accepts only its kind of address
+	for _, line := range []string{"set dns 10.0.0.1/24", "set ip route default via 10.0.0.1/24", "set interfaces eth0 address 10.0.0.1"} {
+		if err := cm.HandleCommand(strings.Fields(line)); err == nil {
+			t.Errorf("Expected error for %q, got nil", line)
+		}
+	}
+
+	// Test case: Invalid value is rejected by the schema
+	if err := cm.HandleCommand([]string{"set", "interfaces", "eth0", "mac", "zz"}); err == nil {
+		t.Error("Expected error for invalid MAC address, got nil")
+	}
+
+	// Test case: Incomplete and unknown commands
+	for _, fields := range [][]string{{"set", "dns"}, {"set", "bogus"}, {"rollback"}} {
+		if err := cm.HandleCommand(fields); err == nil {
+			t.Errorf("Expected error for %q, got nil", strings.Join(fields, " "))
+		}
+	}
+}
+
+// TestContextHelp tests the ? help generated from the command schema.
+func TestContextHelp(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, _ := newCommandManager(t, env)
+	root := cm.CommandTree()
+
+	// Test case: Value nodes show their format hints
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{"set interfaces eth0 address", []string{"<x.x.x.x/x>", "IPv4 address and prefix", "<h:h:h:h:h:h:h:h/x>"}},
+		{"set interfaces eth0 mac", []string{"<h:h:h:h:h:h>", "MAC address"}},
+		{"set dns", []string{"<x.x.x.x>", "IPv4 address"}},
+		{"set dns 8.8.8.8", []string{"<Enter>"}},
+	}
+	for _, tt := range tests {
+		node := schema.Walk(root, strings.Fields(tt.line))
+		if node == nil {
+			t.Fatalf("No schema node for %q", tt.line)
+		}
+		help := strings.Join(schema.Help(node), "\n")
+		for _, want := range tt.expected {
+			if !strings.Contains(help, want) {
+				t.Errorf("Help for %q: expected %q in\n%s", tt.line, want, help)
+			}
+		}
+	}
+
+	// Test case: Every node carries a description
+	var check func(n *schema.Node, path string)
+	check = func(n *schema.Node, path string) {
+		for _, c := range n.Children {
+			p := strings.TrimSpace(path + " " + c.Name())
+			if c.Help == "" {
+				t.Errorf("Node %q has no help text", p)
+			}
+			check(c, p)
+		}
+	}
+	check(root, "")
+}
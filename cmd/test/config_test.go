@@ -1,8 +1,12 @@
 package test
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"configure/internal/config"
 )
@@ -29,6 +33,23 @@ func TestLoadConfig(t *testing.T) {
 	if cfg != nil {
 		t.Error("Expected config to be nil, got non-nil")
 	}
+
+	// Test case: Read-only load falls back to the running config, then defaults
+	cm := config.NewConfigManager(env.BootConfig, env.RunningConfig)
+	cm.LoadReadOnly()
+	if cm.GetRunningConfig().Hostname != "test-router" {
+		t.Errorf("Expected running config to be loaded, got %+v", cm.GetRunningConfig())
+	}
+	os.Remove(env.RunningConfig)
+	cm.LoadReadOnly()
+	if !cm.GetConfig().Equal(config.NewConfig()) {
+		t.Errorf("Expected default config, got %+v", cm.GetConfig())
+	}
+	for _, path := range []string{env.BootConfig, env.RunningConfig} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected read-only load not to create %s, got %v", path, err)
+		}
+	}
 }
 
 // TestSaveConfig tests the saveConfig function.
@@ -57,3 +78,371 @@ func TestSaveConfig(t *testing.T) {
 		t.Errorf("Expected %d DNS servers, got %d", len(cfg.DNS), len(loadedCfg.DNS))
 	}
 }
+
+// TestCandidateCommitSave tests that candidate, running and boot configurations
+// only change on their respective operations.
+func TestCandidateCommitSave(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm := env.ConfigManager
+
+	// Test case: Editing the candidate leaves running and boot untouched
+	cm.SetDNS([]string{"8.8.8.8"})
+	if len(cm.GetRunningConfig().DNS) != 0 {
+		t.Errorf("Expected running DNS to be empty, got %v", cm.GetRunningConfig().DNS)
+	}
+
+	// Test case: Commit updates the running config file only
+	if err := cm.Commit(); err != nil {
+		t.Fatalf("Failed to commit config: %v", err)
+	}
+	running, err := config.LoadConfig(env.RunningConfig)
+	if err != nil {
+		t.Fatalf("Failed to load running config: %v", err)
+	}
+	if len(running.DNS) != 1 || running.DNS[0] != "8.8.8.8" {
+		t.Errorf("Expected running DNS [8.8.8.8], got %v", running.DNS)
+	}
+	boot, err := config.LoadConfig(env.BootConfig)
+	if err != nil {
+		t.Fatalf("Failed to load boot config: %v", err)
+	}
+	if len(boot.DNS) != 0 {
+		t.Errorf("Expected boot DNS to be empty before save, got %v", boot.DNS)
+	}
+
+	// Test case: Save copies the running config to the boot config file
+	if err := cm.Save(); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	boot, err = config.LoadConfig(env.BootConfig)
+	if err != nil {
+		t.Fatalf("Failed to load boot config: %v", err)
+	}
+	if len(boot.DNS) != 1 || boot.DNS[0] != "8.8.8.8" {
+		t.Errorf("Expected boot DNS [8.8.8.8], got %v", boot.DNS)
+	}
+}
+
+// TestSaveCandidate tests that uncommitted changes outlive the ConfigManager.
+func TestSaveCandidate(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm := env.ConfigManager
+
+	// Test case: Uncommitted changes are only restored on request
+	cm.SetDefaultRoute("10.0.0.1")
+	if err := cm.SaveCandidate(); err != nil {
+		t.Fatalf("Failed to save candidate: %v", err)
+	}
+	next := config.NewConfigManager(env.BootConfig, env.RunningConfig)
+	if err := next.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if next.GetConfig().DefaultRoute != "" {
+		t.Errorf("Expected loaded candidate default route to be empty, got %s", next.GetConfig().DefaultRoute)
+	}
+	if err := next.RestoreCandidate(); err != nil {
+		t.Fatalf("Failed to restore candidate: %v", err)
+	}
+	if next.GetConfig().DefaultRoute != "10.0.0.1" {
+		t.Errorf("Expected candidate default route 10.0.0.1, got %s", next.GetConfig().DefaultRoute)
+	}
+	if next.GetRunningConfig().DefaultRoute != "" {
+		t.Errorf("Expected running default route to be empty, got %s", next.GetRunningConfig().DefaultRoute)
+	}
+
+	// Test case: Changes made against an older running configuration are refused
+	other := config.NewConfigManager(env.BootConfig, env.RunningConfig)
+	if err := other.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	other.SetDNS([]string{"8.8.8.8"})
+	if err := other.Commit(); err != nil {
+		t.Fatalf("Failed to commit config: %v", err)
+	}
+	if err := other.RestoreCandidate(); !errors.Is(err, config.ErrStaleCandidate) {
+		t.Errorf("Expected ErrStaleCandidate, got %v", err)
+	}
+	if other.GetConfig().DefaultRoute != "" {
+		t.Errorf("Expected stale candidate to be ignored, got default route %s", other.GetConfig().DefaultRoute)
+	}
+
+	// Test case: Once committed, the persisted candidate is removed
+	if err := next.Commit(); err != nil {
+		t.Fatalf("Failed to commit config: %v", err)
+	}
+	if err := next.SaveCandidate(); err != nil {
+		t.Fatalf("Failed to save candidate: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(env.RunningConfig))
+	if err != nil {
+		t.Fatalf("Failed to read config directory: %v", err)
+	}
+	for _, e := range entries {
+		if strings.Contains(e.Name(), "candidate") {
+			t.Errorf("Expected no persisted candidate after commit, found %s", e.Name())
+		}
+	}
+}
+
+// TestDiff tests the Diff and CompareCommands functions.
+func TestDiff(t *testing.T) {
+	old := &config.Config{
+		Hostname: "test-router",
+		Interfaces: map[string]config.InterfaceConfig{
+			"eth0": {Addresses: config.AddressList{"192.168.1.1/24"}},
+			"eth1": {Addresses: config.AddressList{"10.0.0.1/24"}, MAC: "00:11:22:33:44:55"},
+		},
+		DNS:          []string{"8.8.8.8", "8.8.4.4"},
+		DefaultRoute: "192.168.1.254",
+	}
+	new := &config.Config{
+		Hostname: "test-router",
+		Interfaces: map[string]config.InterfaceConfig{
+			"eth0": {Addresses: config.AddressList{"192.168.2.1/24"}},
+			"eth2": {Addresses: config.AddressList{"172.16.0.1/16"}},
+		},
+		DNS: []string{"8.8.8.8", "1.1.1.1"},
+	}
+
+	// Test case: Command form of the differences
+	got := config.CompareCommands(config.Diff(old, new))
+	expected := []string{
+		"delete interfaces eth0 address 192.168.1.1/24",
+		"set interfaces eth0 address 192.168.2.1/24",
+		"delete interfaces eth1",
+		"set interfaces eth2 address 172.16.0.1/16",
+		"delete dns 8.8.4.4",
+		"add dns 1.1.1.1",
+		"delete ip route default",
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected commands %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Expected command %d to be %q, got %q", i, expected[i], got[i])
+		}
+	}
+
+	// Test case: Reordered DNS servers are replayed in the new order
+	reordered := old.Clone()
+	reordered.DNS = []string{"8.8.4.4", "8.8.8.8"}
+	got = config.CompareCommands(config.Diff(old, reordered))
+	expected = []string{
+		"delete dns 8.8.8.8",
+		"delete dns 8.8.4.4",
+		"add dns 8.8.4.4",
+		"add dns 8.8.8.8",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected commands %v, got %v", expected, got)
+	}
+
+	// Test case: Unified diff of identical configs is empty
+	diff, err := config.UnifiedDiff("running", "candidate", old, old.Clone())
+	if err != nil {
+		t.Fatalf("UnifiedDiff failed: %v", err)
+	}
+	if diff != "" {
+		t.Errorf("Expected empty diff, got %q", diff)
+	}
+
+	// Test case: Unified diff shows removed and added lines
+	diff, err = config.UnifiedDiff("running", "candidate", old, new)
+	if err != nil {
+		t.Fatalf("UnifiedDiff failed: %v", err)
+	}
+	for _, line := range []string{"--- running", "+++ candidate", "-            - 192.168.1.1/24", "+            - 192.168.2.1/24", "+    - 1.1.1.1"} {
+		if !strings.Contains(diff, line+"\n") {
+			t.Errorf("Expected diff to contain %q, got:\n%s", line, diff)
+		}
+	}
+}
+
+// TestPendingRollback tests persisting and claiming the commit-confirm record.
+func TestPendingRollback(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm := env.ConfigManager
+
+	// Test case: No record exists
+	p, err := cm.PendingRollback()
+	if err != nil || p != nil {
+		t.Fatalf("Expected no pending rollback, got %v, %v", p, err)
+	}
+
+	// Test case: Record survives a new ConfigManager
+	pending := &config.PendingRollback{
+		ID:       "test-1",
+		Deadline: time.Now().Add(time.Minute),
+		Previous: cm.GetRunningConfig().Clone(),
+	}
+	if err := cm.SetPendingRollback(pending); err != nil {
+		t.Fatalf("Failed to set pending rollback: %v", err)
+	}
+	other := config.NewConfigManager(env.BootConfig, env.RunningConfig)
+	p, err = other.PendingRollback()
+	if err != nil || p == nil {
+		t.Fatalf("Expected pending rollback, got %v, %v", p, err)
+	}
+	if p.ID != pending.ID || p.Previous.Hostname != "test-router" {
+		t.Errorf("Expected pending rollback %s for test-router, got %s for %s", pending.ID, p.ID, p.Previous.Hostname)
+	}
+
+	// Test case: Claiming with a different ID leaves the record in place
+	if p, err := other.ClaimPendingRollback("test-2"); err != nil || p != nil {
+		t.Errorf("Expected nothing to claim for other ID, got %v, %v", p, err)
+	}
+	if p, err := cm.PendingRollback(); err != nil || p == nil || p.ID != "test-1" {
+		t.Errorf("Expected pending rollback test-1 to be put back, got %v, %v", p, err)
+	}
+
+	// Test case: The record can only be claimed once
+	if p, err := other.ClaimPendingRollback("test-1"); err != nil || p == nil {
+		t.Errorf("Expected to claim pending rollback, got %v, %v", p, err)
+	}
+	if p, err := cm.ClaimPendingRollback("test-1"); err != nil || p != nil {
+		t.Errorf("Expected pending rollback to be claimed already, got %v, %v", p, err)
+	}
+}
+
+// TestArchive tests the commit archive and its retention limit.
+func TestArchive(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm := env.ConfigManager
+
+	// Test case: Empty archive
+	revs, err := cm.Revisions()
+	if err != nil || len(revs) != 0 {
+		t.Fatalf("Expected empty archive, got %v, %v", revs, err)
+	}
+
+	// Test case: Old revisions are pruned beyond the limit
+	cm.SetCommitRevisions(2)
+	for _, dns := range []string{"8.8.8.8", "8.8.4.4", "1.1.1.1"} {
+		cm.SetDNS([]string{dns})
+		if err := cm.Commit(); err != nil {
+			t.Fatalf("Failed to commit config: %v", err)
+		}
+		if _, err := cm.Archive("tester", "dns "+dns); err != nil {
+			t.Fatalf("Failed to archive config: %v", err)
+		}
+	}
+	revs, err = cm.Revisions()
+	if err != nil {
+		t.Fatalf("Failed to list revisions: %v", err)
+	}
+	if len(revs) != 2 {
+		t.Fatalf("Expected 2 revisions, got %d", len(revs))
+	}
+
+	// Test case: Revision 0 is the newest commit
+	rev, err := cm.Revision(0)
+	if err != nil {
+		t.Fatalf("Failed to read revision 0: %v", err)
+	}
+	if rev.User != "tester" || rev.Comment != "dns 1.1.1.1" || rev.Config.DNS[0] != "1.1.1.1" {
+		t.Errorf("Unexpected revision 0: %+v", rev)
+	}
+	rev, err = cm.Revision(1)
+	if err != nil {
+		t.Fatalf("Failed to read revision 1: %v", err)
+	}
+	if rev.Config.DNS[0] != "8.8.4.4" {
+		t.Errorf("Expected revision 1 to have DNS 8.8.4.4, got %v", rev.Config.DNS)
+	}
+
+	// Test case: Pruned revision does not exist
+	if _, err := cm.Revision(2); err == nil {
+		t.Error("Expected error for pruned revision, got nil")
+	}
+}
+
+// TestValidate tests the cross-field validation of configurations.
+func TestValidate(t *testing.T) {
+	valid := &config.Config{
+		Interfaces: map[string]config.InterfaceConfig{
+			"eth0": {Addresses: config.AddressList{"192.168.1.10/24"}, MAC: "02:11:22:33:44:55"},
+			"eth1": {Addresses: config.AddressList{"2001:db8::1/64"}},
+		},
+		DefaultRoute: "192.168.1.1",
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid configuration, got %v", err)
+	}
+
+	// Test case: Route without interface addresses is not checked
+	if err := (&config.Config{DefaultRoute: "10.0.0.1"}).Validate(); err != nil {
+		t.Errorf("Expected default route without interfaces to be valid, got %v", err)
+	}
+
+	// Test case: Every violation is reported with its path
+	tests := []struct {
+		name     string
+		ifaces   map[string]config.InterfaceConfig
+		route    string
+		expected []string
+	}{
+		{"unreachable route", map[string]config.InterfaceConfig{"eth0": {Addresses: config.AddressList{"192.168.1.10/24"}}}, "10.0.0.1",
+			[]string{"ip route default via: 10.0.0.1 is not reachable"}},
+		{"route to own address", map[string]config.InterfaceConfig{"eth0": {Addresses: config.AddressList{"192.168.1.10/24"}}}, "192.168.1.10",
+			[]string{"ip route default via: 192.168.1.10 is the address of eth0"}},
+		{"duplicate address", map[string]config.InterfaceConfig{"eth0": {Addresses: config.AddressList{"192.168.1.10/24"}}, "eth1": {Addresses: config.AddressList{"192.168.1.10/24"}}}, "",
+			[]string{"interfaces eth1 address: 192.168.1.10 is also configured on eth0"}},
+		{"overlapping subnets", map[string]config.InterfaceConfig{"eth0": {Addresses: config.AddressList{"10.0.0.1/16"}}, "eth1": {Addresses: config.AddressList{"10.0.5.1/24"}}}, "",
+			[]string{"interfaces eth1 address: subnet 10.0.5.0/24 overlaps 10.0.0.0/16 on eth0"}},
+		{"network and broadcast", map[string]config.InterfaceConfig{"eth0": {Addresses: config.AddressList{"192.168.1.0/24"}}, "eth1": {Addresses: config.AddressList{"10.0.0.255/24"}}}, "",
+			[]string{"interfaces eth0 address: 192.168.1.0/24 is the network address", "interfaces eth1 address: 10.0.0.255/24 is the broadcast address"}},
+		{"bad MACs", map[string]config.InterfaceConfig{"eth0": {MAC: "00:00:00:00:00:00"}, "eth1": {MAC: "01:00:5e:00:00:01"}}, "",
+			[]string{"interfaces eth0 mac: 00:00:00:00:00:00 is the all-zero address", "interfaces eth1 mac: 01:00:5e:00:00:01 is a multicast address"}},
+		{"speed without duplex", map[string]config.InterfaceConfig{"eth0": {Speed: "1000"}, "eth1": {Duplex: "full"}}, "",
+			[]string{"interfaces eth0 speed: speed 1000 requires duplex half or full", "interfaces eth1 duplex: duplex full requires a fixed speed"}},
+		{"half duplex gigabit", map[string]config.InterfaceConfig{"eth0": {Speed: "1000", Duplex: "half"}}, "",
+			[]string{"interfaces eth0 duplex: half duplex is only supported at 10 or 100 Mbit/s"}},
+		{"IPv6 on small MTU", map[string]config.InterfaceConfig{"eth0": {Addresses: config.AddressList{"2001:db8::1/64"}, MTU: 1200}}, "",
+			[]string{"interfaces eth0 mtu: 1200 is below the IPv6 minimum of 1280"}},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{Interfaces: tt.ifaces, DefaultRoute: tt.route}
+		err := cfg.Validate()
+		if err == nil {
+			t.Errorf("%s: expected validation error, got nil", tt.name)
+			continue
+		}
+		for _, want := range tt.expected {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: expected %q in %q", tt.name, want, err.Error())
+			}
+		}
+	}
+
+	// Test case: Static route violations are reported with their path
+	routes := &config.Config{
+		DefaultRoute: "192.168.1.1",
+		Protocols: config.ProtocolsConfig{Static: config.StaticConfig{Routes: map[string]config.StaticRoute{
+			"0.0.0.0/0":     {NextHops: map[string]config.RouteTarget{"192.168.1.2": {}}},
+			"10.0.0.1/8":    {Blackhole: &config.RouteTarget{}},
+			"10.1.0.0/16":   {NextHops: map[string]config.RouteTarget{"2001:db8::1": {}}, Blackhole: &config.RouteTarget{}},
+			"10.2.0.0/16":   {Interfaces: map[string]config.RouteTarget{"eth0": {Distance: 300}}},
+			"2001:db8::/32": {},
+		}}},
+	}
+	err := routes.Validate()
+	for _, want := range []string{
+		"protocols static route 0.0.0.0/0: conflicts with ip route default via 192.168.1.1",
+		"protocols static route 10.0.0.1/8: 10.0.0.1/8 has host bits set",
+		"protocols static route 10.1.0.0/16 next-hop 2001:db8::1: next-hop and destination are of different address families",
+		"protocols static route 10.1.0.0/16 blackhole: distance 1 is also used by a next-hop or interface",
+		"protocols static route 10.2.0.0/16 interface eth0 distance: 300 is out of range",
+		"protocols static route 2001:db8::/32: no next-hop, interface or blackhole configured",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %v", want, err)
+		}
+	}
+
+	// Test case: /31 and /32 addresses have no network or broadcast address
+	p2p := &config.Config{Interfaces: map[string]config.InterfaceConfig{"eth0": {Addresses: config.AddressList{"10.0.0.0/31"}}, "lo0": {Addresses: config.AddressList{"10.1.1.1/32"}}}}
+	if err := p2p.Validate(); err != nil {
+		t.Errorf("Expected point-to-point addresses to be valid, got %v", err)
+	}
+}
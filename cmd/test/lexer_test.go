@@ -0,0 +1,81 @@
+package test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"configure/internal/lexer"
+)
+
+// TestLexer tests splitting command lines into words.
+func TestLexer(t *testing.T) {
+	// Test case: Quoting, escapes, tabs and comments
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{"set dns 8.8.8.8", []string{"set", "dns", "8.8.8.8"}},
+		{"set\tdns  \t8.8.8.8 ", []string{"set", "dns", "8.8.8.8"}},
+		{`commit comment "first  commit"`, []string{"commit", "comment", "first  commit"}},
+		{`commit comment 'it''s'`, []string{"commit", "comment", "its"}},
+		{`commit comment "say \"hi\" \n"`, []string{"commit", "comment", `say "hi" \n`}},
+		{`commit comment 'a\b'`, []string{"commit", "comment", `a\b`}},
+		{`commit comment a\ b`, []string{"commit", "comment", "a b"}},
+		{"commit comment 日本語 テスト", []string{"commit", "comment", "日本語", "テスト"}},
+		{"set dns 8.8.8.8 # primary", []string{"set", "dns", "8.8.8.8"}},
+		{"set dns a#b", []string{"set", "dns", "a#b"}},
+		{`set dns "#x"`, []string{"set", "dns", "#x"}},
+		{"# only a comment", nil},
+		{`""`, []string{""}},
+	}
+	for _, tt := range tests {
+		got, err := lexer.Split(tt.line)
+		if err != nil {
+			t.Errorf("Split(%q) failed: %v", tt.line, err)
+			continue
+		}
+		if strings.Join(got, "|") != strings.Join(tt.expected, "|") || len(got) != len(tt.expected) {
+			t.Errorf("Split(%q): expected %q, got %q", tt.line, tt.expected, got)
+		}
+	}
+
+	// Test case: Unterminated quotes and trailing backslashes report their column
+	errTests := []struct {
+		line   string
+		column int
+	}{
+		{`commit comment "abc`, 16},
+		{`commit comment 'abc`, 16},
+		{`日本 "abc`, 4},
+		{`set dns \`, 9},
+	}
+	for _, tt := range errTests {
+		_, err := lexer.Split(tt.line)
+		var lexErr *lexer.Error
+		if !errors.As(err, &lexErr) {
+			t.Errorf("Split(%q): expected *lexer.Error, got %v", tt.line, err)
+			continue
+		}
+		if lexErr.Column != tt.column {
+			t.Errorf("Split(%q): expected error at column %d, got %d", tt.line, tt.column, lexErr.Column)
+		}
+	}
+
+	// Test case: Partial lines for completion
+	words, current, ok := lexer.SplitPartial(`commit comment "abc`)
+	if !ok || strings.Join(words, " ") != "commit comment" || current != "abc" {
+		t.Errorf("SplitPartial: expected [commit comment] and abc, got %q, %q, %v", words, current, ok)
+	}
+	if _, _, ok := lexer.SplitPartial("set dns # c"); ok {
+		t.Error("SplitPartial: expected no completion inside a comment")
+	}
+
+	// Test case: Quoted words split back to themselves
+	for _, word := range []string{"eth0", "two words", "it's", `a"b\c`, "#x", ""} {
+		got, err := lexer.Split("set " + lexer.Quote(word))
+		if err != nil || len(got) != 2 || got[1] != word {
+			t.Errorf("Quote(%q) = %s does not split back, got %q, %v", word, lexer.Quote(word), got, err)
+		}
+	}
+}
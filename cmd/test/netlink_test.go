@@ -0,0 +1,135 @@
+//go:build linux
+
+package test
+
+import (
+	"errors"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"configure/internal/system"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+)
+
+// TestNetlinkBackend tests the netlink backend in a throwaway network namespace.
+func TestNetlinkBackend(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Skip: creating a network namespace requires root")
+	}
+
+	// Network namespaces are per thread, so keep this goroutine on one thread
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	origin, err := netns.Get()
+	if err != nil {
+		t.Fatalf("Failed to get current network namespace: %v", err)
+	}
+	defer origin.Close()
+	ns, err := netns.New()
+	if err != nil {
+		t.Skipf("Skip: cannot create network namespace: %v", err)
+	}
+	defer ns.Close()
+	defer netns.Set(origin)
+
+	veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "veth0"}, PeerName: "veth1"}
+	if err := netlink.LinkAdd(veth); err != nil {
+		t.Fatalf("Failed to create veth link: %v", err)
+	}
+	for _, name := range []string{"veth0", "veth1"} {
+		link, err := netlink.LinkByName(name)
+		if err != nil {
+			t.Fatalf("Failed to find veth link: %v", err)
+		}
+		if err := netlink.LinkSetUp(link); err != nil {
+			t.Fatalf("Failed to set veth link up: %v", err)
+		}
+	}
+
+	backend := system.NewNetlink()
+
+	// Test case: Operations are idempotent
+	for i := 0; i < 2; i++ {
+		if err := backend.SetLinkMAC("veth0", "02:00:00:00:00:01"); err != nil {
+			t.Errorf("SetLinkMAC failed: %v", err)
+		}
+		if err := backend.AddAddress("veth0", "10.0.0.1/24"); err != nil {
+			t.Errorf("AddAddress failed: %v", err)
+		}
+		if err := backend.ReplaceDefaultRoute("10.0.0.254"); err != nil {
+			t.Errorf("ReplaceDefaultRoute failed: %v", err)
+		}
+	}
+
+	link, err := netlink.LinkByName("veth0")
+	if err != nil {
+		t.Fatalf("Failed to find veth link: %v", err)
+	}
+	if mac := link.Attrs().HardwareAddr.String(); mac != "02:00:00:00:00:01" {
+		t.Errorf("Expected MAC 02:00:00:00:00:01, got %s", mac)
+	}
+	if !hasAddr(t, link, "10.0.0.1/24") {
+		t.Error("Expected address 10.0.0.1/24 on veth0")
+	}
+	if gw := defaultGateway(t); gw != "10.0.0.254" {
+		t.Errorf("Expected default route via 10.0.0.254, got %q", gw)
+	}
+
+	// Test case: Failures report the operation
+	err = backend.AddAddress("missing0", "10.0.1.1/24")
+	var opErr *system.OpError
+	if !errors.As(err, &opErr) || !strings.Contains(opErr.Op, "missing0") {
+		t.Errorf("Expected operation error for missing0, got %v", err)
+	}
+
+	// Test case: Deleting is idempotent
+	for i := 0; i < 2; i++ {
+		if err := backend.DeleteAddress("veth0", "10.0.0.1/24"); err != nil {
+			t.Errorf("DeleteAddress failed: %v", err)
+		}
+		if err := backend.DeleteDefaultRoute("10.0.0.254"); err != nil {
+			t.Errorf("DeleteDefaultRoute failed: %v", err)
+		}
+	}
+	if hasAddr(t, link, "10.0.0.1/24") {
+		t.Error("Expected address 10.0.0.1/24 to be removed from veth0")
+	}
+	if gw := defaultGateway(t); gw != "" {
+		t.Errorf("Expected no default route, got one via %s", gw)
+	}
+}
+
+// hasAddr reports whether a link has the given address
+func hasAddr(t *testing.T, link netlink.Link, cidr string) bool {
+	t.Helper()
+	addrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
+	if err != nil {
+		t.Fatalf("Failed to list addresses: %v", err)
+	}
+	for _, a := range addrs {
+		if a.IPNet.String() == cidr {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultGateway returns the gateway of the IPv4 default route, or "" if there is none
+func defaultGateway(t *testing.T) string {
+	t.Helper()
+	routes, err := netlink.RouteList(nil, netlink.FAMILY_V4)
+	if err != nil {
+		t.Fatalf("Failed to list routes: %v", err)
+	}
+	for _, r := range routes {
+		if r.Dst == nil || r.Dst.IP.Equal(net.IPv4zero) {
+			return r.Gw.String()
+		}
+	}
+	return ""
+}
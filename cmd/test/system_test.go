@@ -1,23 +1,24 @@
 package test
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
-	"configure/cmd"
+	"configure/internal/config"
+	"configure/internal/plan"
+	"configure/internal/system"
 )
 
-// TestWriteResolvConf tests the WriteResolvConf function.
+// TestWriteResolvConf tests the WriteResolvConf function of the shell backend.
 func TestWriteResolvConf(t *testing.T) {
 	if runtime.GOOS != "linux" {
 		t.Skip("Skip: not running on Linux/WSL")
 	}
 	env := SetupTestEnv(t)
-	cmd.LoadedConfig = env.LoadedConfig
-	// DNSをセット
-	cmd.LoadedConfig.DNS = []string{"8.8.8.8"}
 
 	// Create a temporary resolv.conf file
 	resolvConf := filepath.Join(env.TempDir, "resolv.conf")
@@ -25,7 +26,7 @@ func TestWriteResolvConf(t *testing.T) {
 	defer os.Unsetenv("RESOLV_CONF")
 
 	// Test case: Write resolv.conf
-	err := cmd.WriteResolvConf()
+	err := system.NewShell().WriteResolvConf([]string{"8.8.8.8"})
 	if err != nil {
 		t.Errorf("Failed to write resolv.conf: %v", err)
 	}
@@ -41,32 +42,499 @@ func TestWriteResolvConf(t *testing.T) {
 	}
 }
 
-// TestRestartServices tests the RestartServices function.
+// TestRestartServices tests that commit restarts the resolver services.
 func TestRestartServices(t *testing.T) {
-	if runtime.GOOS != "linux" {
-		t.Skip("Skip: not running on Linux/WSL")
-	}
 	env := SetupTestEnv(t)
-	cmd.LoadedConfig = env.LoadedConfig
+	cm, recorder := newCommandManager(t, env)
 
 	// Test case: Restart services
-	err := cmd.RestartServices()
-	if err != nil {
-		t.Errorf("Failed to restart services: %v", err)
+	cm.SetDNS([]string{"8.8.8.8"})
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
 	}
+
+	expectOps(t, recorder, []string{
+		"write resolv.conf 8.8.8.8",
+		"restart resolvconf.service",
+		"restart systemd-resolved.service",
+	})
 }
 
-// TestSetDefaultRoute tests the SetDefaultRoute function.
+// TestSetDefaultRoute tests that commit adds the default route.
 func TestSetDefaultRoute(t *testing.T) {
-	if runtime.GOOS != "linux" {
-		t.Skip("Skip: not running on Linux/WSL")
-	}
 	env := SetupTestEnv(t)
-	cmd.LoadedConfig = env.LoadedConfig
+	cm, recorder := newCommandManager(t, env)
 
 	// Test case: Set default route
-	err := cmd.SetDefaultRoute()
+	cm.SetDefaultRoute("192.168.1.1")
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+
+	expectOps(t, recorder, []string{
+		"route replace default via 192.168.1.1",
+	})
+
+	// Test case: Deleting removes only the default route via the gateway
+	recorder.Ops = nil
+	if err := cm.HandleCommand([]string{"delete", "ip", "route", "default"}); err != nil {
+		t.Fatalf("delete ip route default failed: %v", err)
+	}
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+	expectOps(t, recorder, []string{
+		"route del default via 192.168.1.1",
+	})
+}
+
+// TestIdempotentCommit tests that commit only touches subsystems whose configuration changed.
+func TestIdempotentCommit(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, recorder := newCommandManager(t, env)
+
+	cm.SetDNS([]string{"8.8.8.8"})
+	cm.SetInterface("eth0", config.InterfaceConfig{Addresses: config.AddressList{"192.168.1.100/24"}})
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+
+	// Test case: Committing without changes performs no operations
+	recorder.Ops = nil
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+	expectOps(t, recorder, nil)
+
+	// Test case: Hostname change does not touch DNS or interfaces
+	cm.GetConfig().Hostname = "other-router"
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+	expectOps(t, recorder, nil)
+	if cm.GetRunningConfig().Hostname != "other-router" {
+		t.Errorf("Expected running hostname other-router, got %s", cm.GetRunningConfig().Hostname)
+	}
+
+	// Test case: Address change only replaces the address
+	cm.SetInterface("eth0", config.InterfaceConfig{Addresses: config.AddressList{"192.168.2.100/24"}})
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+	expectOps(t, recorder, []string{
+		"address del 192.168.1.100/24 dev eth0",
+		"address add 192.168.2.100/24 dev eth0",
+	})
+}
+
+// TestCommitDryRun tests that a dry-run commit plans operations without performing them.
+func TestCommitDryRun(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, recorder := newCommandManager(t, env)
+
+	cm.SetDNS([]string{"8.8.8.8", "1.1.1.1"})
+	cm.SetDefaultRoute("192.168.1.1")
+
+	// Test case: Plan lists every operation
+	p := plan.New(cm.GetRunningConfig(), cm.GetConfig(), config.OriginalLinks{})
+	expected := "write resolv.conf\n" +
+		"    nameserver 8.8.8.8\n" +
+		"    nameserver 1.1.1.1\n" +
+		"restart resolvconf.service\n" +
+		"restart systemd-resolved.service\n" +
+		"route replace default via 192.168.1.1\n"
+	if p.String() != expected {
+		t.Errorf("Expected plan:\n%s\ngot:\n%s", expected, p.String())
+	}
+
+	// Test case: Dry-run commits touch neither the system nor the running config
+	if err := cm.HandleCommand([]string{"commit", "dry-run"}); err != nil {
+		t.Fatalf("commit dry-run failed: %v", err)
+	}
+	cm.SetDryRun(true)
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+	if len(recorder.Ops) != 0 {
+		t.Errorf("Expected no operations, got %q", recorder.Ops)
+	}
+	if len(cm.GetRunningConfig().DNS) != 0 {
+		t.Errorf("Expected running DNS to stay empty, got %v", cm.GetRunningConfig().DNS)
+	}
+}
+
+// TestTransactionalCommit tests that a failing step undoes the steps applied before it.
+func TestTransactionalCommit(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, recorder := newCommandManager(t, env)
+
+	cm.SetDNS([]string{"8.8.8.8"})
+	cm.SetDefaultRoute("192.168.1.1")
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+
+	// Test case: Route failure undoes the address and resolv.conf changes
+	recorder.Ops = nil
+	recorder.Errors["route replace default via 10.0.0.254"] = errors.New("network is unreachable")
+	cm.SetDNS([]string{"1.1.1.1"})
+	cm.SetDefaultRoute("10.0.0.254")
+	cm.SetInterface("eth0", config.InterfaceConfig{Addresses: config.AddressList{"10.0.0.1/24"}})
+	err := cm.HandleCommit()
+	var planErr *plan.Error
+	if !errors.As(err, &planErr) {
+		t.Fatalf("Expected plan error, got %v", err)
+	}
+	if planErr.Step != "route replace default via 10.0.0.254" || planErr.Undone != 2 {
+		t.Errorf("Expected failed route step with 2 undone steps, got %q with %d", planErr.Step, planErr.Undone)
+	}
+	expectOps(t, recorder, []string{
+		"address add 10.0.0.1/24 dev eth0",
+		"write resolv.conf 1.1.1.1",
+		"restart resolvconf.service",
+		"restart systemd-resolved.service",
+		"route replace default via 10.0.0.254",
+		"write resolv.conf 8.8.8.8",
+		"restart resolvconf.service",
+		"restart systemd-resolved.service",
+		"address del 10.0.0.1/24 dev eth0",
+	})
+
+	// Test case: Running config file is unchanged
+	running, err := config.LoadConfig(env.RunningConfig)
 	if err != nil {
-		t.Errorf("Failed to set default route: %v", err)
+		t.Fatalf("Failed to load running config: %v", err)
+	}
+	if running.DefaultRoute != "192.168.1.1" || running.DNS[0] != "8.8.8.8" {
+		t.Errorf("Expected running config to be unchanged, got %+v", running)
+	}
+
+	// Test case: Failing to write the running config restores the hardware address
+	cm.HandleDiscard()
+	recorder.Ops = nil
+	recorder.LinkMACs = map[string]string{"eth0": "02:00:00:00:00:01"}
+	cm.SetInterface("eth0", config.InterfaceConfig{MAC: "00:11:22:33:44:55"})
+	if err := os.Remove(env.RunningConfig); err != nil {
+		t.Fatalf("Failed to remove running config: %v", err)
+	}
+	if err := os.Mkdir(env.RunningConfig, 0755); err != nil {
+		t.Fatalf("Failed to block running config: %v", err)
+	}
+	if err := cm.HandleCommit(); err == nil {
+		t.Fatal("Expected error when the running config cannot be written, got nil")
+	}
+	expectOps(t, recorder, []string{
+		"link set eth0 address 00:11:22:33:44:55",
+		"link set eth0 address 02:00:00:00:00:01",
+	})
+}
+
+// TestInterfaceAddressing tests multiple addresses, DHCP and IPv6 autoconf on one interface.
+func TestInterfaceAddressing(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, recorder := newCommandManager(t, env)
+	recorder.LinkNames = []string{"eth0"}
+	run := func(line ...string) {
+		t.Helper()
+		if err := cm.HandleCommand(line); err != nil {
+			t.Fatalf("%v failed: %v", line, err)
+		}
+	}
+
+	// Test case: IPv4 and IPv6 addresses, DHCPv6 and autoconf coexist
+	run("set", "interfaces", "eth0", "address", "192.168.1.10/24")
+	run("set", "interfaces", "eth0", "address", "192.168.1.11/24")
+	run("set", "interfaces", "eth0", "address", "2001:db8::10/64")
+	run("set", "interfaces", "eth0", "address", "dhcpv6")
+	run("set", "interfaces", "eth0", "ipv6", "address", "autoconf")
+	if err := cm.HandleCommand([]string{"set", "interfaces", "eth0", "address", "192.168.1.10/24"}); err == nil {
+		t.Error("Expected error for an address that is already configured, got nil")
+	}
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+	expectOps(t, recorder, []string{
+		"address add 192.168.1.10/24 dev eth0",
+		"address add 192.168.1.11/24 dev eth0",
+		"address add 2001:db8::10/64 dev eth0",
+		"dhcpv6 start eth0",
+		"ipv6 autoconf on eth0",
+	})
+
+	// Test case: Configuration commands reproduce the interface
+	expected := []string{
+		"set hostname test-router",
+		"set interfaces eth0 address 192.168.1.10/24",
+		"set interfaces eth0 address 192.168.1.11/24",
+		"set interfaces eth0 address 2001:db8::10/64",
+		"set interfaces eth0 address dhcpv6",
+		"set interfaces eth0 ipv6 address autoconf",
+	}
+	if got := config.Commands(cm.GetConfig()); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected commands %q, got %q", expected, got)
+	}
+
+	// Test case: Single entries are removed without touching the others
+	recorder.Ops = nil
+	run("delete", "interfaces", "eth0", "address", "192.168.1.11/24")
+	run("delete", "interfaces", "eth0", "address", "dhcpv6")
+	run("delete", "interfaces", "eth0", "ipv6", "address", "autoconf")
+	run("set", "interfaces", "eth0", "address", "dhcp")
+	if err := cm.HandleCommand([]string{"delete", "interfaces", "eth0", "address", "10.9.9.9/24"}); err == nil {
+		t.Error("Expected error for an address that is not configured, got nil")
+	}
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+	expectOps(t, recorder, []string{
+		"address del 192.168.1.11/24 dev eth0",
+		"dhcpv6 stop eth0",
+		"dhcp start eth0",
+		"ipv6 autoconf off eth0",
+	})
+
+	// Test case: Deleting the interface removes every entry
+	recorder.Ops = nil
+	run("delete", "interfaces", "eth0")
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+	expectOps(t, recorder, []string{
+		"address del 192.168.1.10/24 dev eth0",
+		"address del 2001:db8::10/64 dev eth0",
+		"dhcp stop eth0",
+	})
+}
+
+// TestLinkSettings tests that description, MTU, speed, duplex, offload and
+// admin state are applied on commit and return to their defaults when deleted.
+func TestLinkSettings(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, recorder := newCommandManager(t, env)
+	recorder.LinkNames = []string{"eth0"}
+	recorder.LinkMTUs = map[string]int{"eth0": 1450}
+	run := func(line ...string) {
+		t.Helper()
+		if err := cm.HandleCommand(line); err != nil {
+			t.Fatalf("%v failed: %v", line, err)
+		}
+	}
+
+	// Test case: Every setting is applied, the admin state last
+	run("set", "interfaces", "eth0", "description", "Uplink to core-1")
+	run("set", "interfaces", "eth0", "mtu", "9000")
+	run("set", "interfaces", "eth0", "speed", "1000")
+	run("set", "interfaces", "eth0", "duplex", "full")
+	run("set", "interfaces", "eth0", "offload", "gro")
+	run("set", "interfaces", "eth0", "offload", "tso")
+	run("set", "interfaces", "eth0", "address", "192.168.1.10/24")
+	run("set", "interfaces", "eth0", "disable")
+	for _, line := range [][]string{
+		{"set", "interfaces", "eth0", "mtu", "20000"},
+		{"set", "interfaces", "eth0", "speed", "123"},
+		{"set", "interfaces", "eth0", "offload", "gro"},
+	} {
+		if err := cm.HandleCommand(line); err == nil {
+			t.Errorf("Expected error for %v, got nil", line)
+		}
+	}
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+	expectOps(t, recorder, []string{
+		"link set eth0 mtu 9000",
+		"link set eth0 alias Uplink to core-1",
+		"ethtool eth0 speed 1000 duplex full autoneg off",
+		"ethtool eth0 offload gro on",
+		"ethtool eth0 offload tso on",
+		"address add 192.168.1.10/24 dev eth0",
+		"link set eth0 down",
+	})
+
+	// Test case: Configuration commands reproduce the settings
+	expected := []string{
+		"set hostname test-router",
+		"set interfaces eth0 address 192.168.1.10/24",
+		"set interfaces eth0 description 'Uplink to core-1'",
+		"set interfaces eth0 mtu 9000",
+		"set interfaces eth0 speed 1000",
+		"set interfaces eth0 duplex full",
+		"set interfaces eth0 offload gro",
+		"set interfaces eth0 offload tso",
+		"set interfaces eth0 disable",
+	}
+	if got := config.Commands(cm.GetConfig()); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected commands %q, got %q", expected, got)
+	}
+
+	// Test case: Deleted settings return to their defaults, the MTU to the one
+	// the link had before
+	recorder.Ops = nil
+	run("delete", "interfaces", "eth0", "description")
+	run("delete", "interfaces", "eth0", "mtu")
+	run("delete", "interfaces", "eth0", "speed")
+	run("delete", "interfaces", "eth0", "duplex")
+	run("delete", "interfaces", "eth0", "offload", "gro")
+	run("delete", "interfaces", "eth0", "disable")
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+	expectOps(t, recorder, []string{
+		"link set eth0 mtu 1450",
+		"link set eth0 alias",
+		"ethtool eth0 autoneg on",
+		"ethtool eth0 offload gro off",
+		"link set eth0 up",
+	})
+
+	// Test case: Inconsistent speed and duplex are refused on commit
+	run("set", "interfaces", "eth0", "speed", "100")
+	if err := cm.HandleCommit(); err == nil || !strings.Contains(err.Error(), "requires duplex half or full") {
+		t.Errorf("Expected speed without duplex to be refused, got %v", err)
+	}
+
+	// Test case: A failed commit restores the MTU the link had
+	recorder.Ops = nil
+	recorder.Errors["link set eth0 alias Uplink"] = errors.New("operation not permitted")
+	run("delete", "interfaces", "eth0", "speed")
+	run("set", "interfaces", "eth0", "mtu", "9000")
+	run("set", "interfaces", "eth0", "description", "Uplink")
+	if err := cm.HandleCommit(); err == nil {
+		t.Fatal("Expected commit to fail, got nil")
+	}
+	expectOps(t, recorder, []string{
+		"link set eth0 mtu 9000",
+		"link set eth0 alias Uplink",
+		"link set eth0 mtu 1450",
+	})
+}
+
+// TestRestoreMAC tests that removing a configured MAC address restores the one
+// the link had before.
+func TestRestoreMAC(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, recorder := newCommandManager(t, env)
+	recorder.LinkNames = []string{"eth0"}
+	recorder.LinkMACs = map[string]string{"eth0": "02:00:00:00:00:01"}
+	run := func(line ...string) {
+		t.Helper()
+		if err := cm.HandleCommand(line); err != nil {
+			t.Fatalf("%v failed: %v", line, err)
+		}
+	}
+
+	// Test case: Without a recorded address, removing the MAC leaves the link
+	old := &config.Config{Interfaces: map[string]config.InterfaceConfig{"eth0": {MAC: "00:11:22:33:44:55"}}}
+	if p := plan.New(old, config.NewEmptyConfig(), config.OriginalLinks{}); p.String() != "" {
+		t.Errorf("Expected empty plan, got:\n%s", p.String())
+	}
+	original := config.OriginalLinks{MACs: map[string]string{"eth0": "02:00:00:00:00:01"}}
+	if p := plan.New(old, config.NewEmptyConfig(), original); p.String() != "link set eth0 address 02:00:00:00:00:01\n" {
+		t.Errorf("Expected the recorded address to be restored, got:\n%s", p.String())
+	}
+
+	// Test case: Deleting the MAC restores the address recorded when it was set
+	run("set", "interfaces", "eth0", "mac", "00:11:22:33:44:55")
+	run("set", "interfaces", "eth0", "address", "192.168.1.10/24")
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+	recorder.LinkMACs["eth0"] = "00:11:22:33:44:55"
+	recorder.Ops = nil
+	run("delete", "interfaces", "eth0", "mac")
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+	expectOps(t, recorder, []string{"link set eth0 address 02:00:00:00:00:01"})
+
+	// Test case: Deleting the interface restores the address too
+	recorder.LinkMACs["eth0"] = "02:00:00:00:00:01"
+	run("set", "interfaces", "eth0", "mac", "00:11:22:33:44:66")
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+	recorder.LinkMACs["eth0"] = "00:11:22:33:44:66"
+	recorder.Ops = nil
+	run("delete", "interfaces", "eth0")
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+	expectOps(t, recorder, []string{
+		"address del 192.168.1.10/24 dev eth0",
+		"link set eth0 address 02:00:00:00:00:01",
+	})
+}
+
+// TestStaticRoutes tests that static routes are applied idempotently on commit,
+// with targets of equal distance combined into ECMP routes.
+func TestStaticRoutes(t *testing.T) {
+	env := SetupTestEnv(t)
+	cm, recorder := newCommandManager(t, env)
+	recorder.LinkNames = []string{"eth0"}
+	run := func(line string) {
+		t.Helper()
+		if err := cm.HandleCommand(strings.Fields(line)); err != nil {
+			t.Fatalf("%s failed: %v", line, err)
+		}
+	}
+
+	// Test case: Next-hops, interfaces, blackholes and IPv6 routes are installed
+	run("set protocols static route 10.0.0.0/8 next-hop 192.168.1.1")
+	run("set protocols static route 10.0.0.0/8 next-hop 192.168.1.2")
+	run("set protocols static route 10.0.0.0/8 blackhole distance 200")
+	run("set protocols static route 172.16.0.0/12 interface eth0")
+	run("set protocols static route 2001:db8:1::/48 next-hop 2001:db8::1 distance 10")
+	for _, line := range []string{
+		"set protocols static route 10.0.0.1/8 next-hop 192.168.1.1",
+		"set protocols static route 10.0.0.0/8 next-hop 192.168.1.1 distance 256",
+		"set protocols static route 10.0.0.0/8 interface wg9",
+		"delete protocols static route 192.0.2.0/24",
+	} {
+		if err := cm.HandleCommand(strings.Fields(line)); err == nil {
+			t.Errorf("Expected error for %q, got nil", line)
+		}
+	}
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+	expectOps(t, recorder, []string{
+		"route replace 10.0.0.0/8 metric 1 nexthop via 192.168.1.1 nexthop via 192.168.1.2",
+		"route replace blackhole 10.0.0.0/8 metric 200",
+		"route replace 172.16.0.0/12 dev eth0 metric 1",
+		"route replace 2001:db8:1::/48 via 2001:db8::1 metric 10",
+	})
+
+	// Test case: Only changed routes are touched
+	recorder.Ops = nil
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+	expectOps(t, recorder, nil)
+	run("delete protocols static route 10.0.0.0/8 next-hop 192.168.1.2")
+	run("set protocols static route 2001:db8:1::/48 next-hop 2001:db8::1 distance 20")
+	run("delete protocols static route 172.16.0.0/12")
+	if err := cm.HandleCommit(); err != nil {
+		t.Fatalf("HandleCommit failed: %v", err)
+	}
+	expectOps(t, recorder, []string{
+		"route del 172.16.0.0/12 dev eth0 metric 1",
+		"route del 2001:db8:1::/48 via 2001:db8::1 metric 10",
+		"route replace 10.0.0.0/8 via 192.168.1.1 metric 1",
+		"route replace 2001:db8:1::/48 via 2001:db8::1 metric 20",
+	})
+
+	// Test case: A failed route is undone with the rest of the commit
+	recorder.Ops = nil
+	recorder.Errors["route replace 10.0.0.0/8 via 192.168.1.9 metric 1"] = errors.New("nexthop has invalid gateway")
+	run("delete protocols static route 10.0.0.0/8 blackhole")
+	run("delete protocols static route 10.0.0.0/8 next-hop 192.168.1.1")
+	run("set protocols static route 10.0.0.0/8 next-hop 192.168.1.9")
+	if err := cm.HandleCommit(); err == nil {
+		t.Fatal("Expected commit to fail")
 	}
+	expectOps(t, recorder, []string{
+		"route del blackhole 10.0.0.0/8 metric 200",
+		"route replace 10.0.0.0/8 via 192.168.1.9 metric 1",
+		"route replace blackhole 10.0.0.0/8 metric 200",
+	})
 }
@@ -5,7 +5,9 @@ import (
 	"path/filepath"
 	"testing"
 
+	"configure/cmd"
 	"configure/internal/config"
+	"configure/internal/system"
 )
 
 // TestEnv represents the test environment
@@ -25,8 +27,8 @@ func SetupTestEnv(t *testing.T) *TestEnv {
 	}
 
 	// Create test configuration files
-	bootConfig := filepath.Join(tempDir, "boot.config.yaml")
-	runningConfig := filepath.Join(tempDir, "running.config.yaml")
+	bootConfig := filepath.Join(tempDir, cmd.BootConfigFile)
+	runningConfig := filepath.Join(tempDir, cmd.RunningConfigFile)
 
 	// Create initial configuration
 	cfg := &config.Config{
@@ -36,10 +38,14 @@ func SetupTestEnv(t *testing.T) *TestEnv {
 	}
 
 	// Save initial configuration
+	for _, path := range []string{bootConfig, runningConfig} {
+		if err := config.SaveConfig(cfg, path); err != nil {
+			t.Fatalf("Failed to save initial config: %v", err)
+		}
+	}
 	cm := config.NewConfigManager(bootConfig, runningConfig)
-	cm.Config = cfg
-	if err := cm.Save(); err != nil {
-		t.Fatalf("Failed to save initial config: %v", err)
+	if err := cm.Load(); err != nil {
+		t.Fatalf("Failed to load initial config: %v", err)
 	}
 
 	// Clean up after test
@@ -68,3 +74,29 @@ func (env *TestEnv) SaveConfig(t *testing.T) {
 		t.Fatalf("Failed to save config: %v", err)
 	}
 }
+
+// expectOps checks that the recorder saw exactly the expected operations
+func expectOps(t *testing.T, recorder *system.Recorder, expected []string) {
+	t.Helper()
+	if len(recorder.Ops) != len(expected) {
+		t.Fatalf("Expected operations %q, got %q", expected, recorder.Ops)
+	}
+	for i := range expected {
+		if recorder.Ops[i] != expected[i] {
+			t.Errorf("Expected operation %d to be %q, got %q", i, expected[i], recorder.Ops[i])
+		}
+	}
+}
+
+// newCommandManager creates a CommandManager for the test environment that
+// records system operations instead of performing them
+func newCommandManager(t *testing.T, env *TestEnv) (*cmd.CommandManager, *system.Recorder) {
+	t.Helper()
+	cm, err := cmd.NewCommandManager(env.BootConfig, env.RunningConfig)
+	if err != nil {
+		t.Fatalf("Failed to create command manager: %v", err)
+	}
+	recorder := system.NewRecorder()
+	cm.SetBackend(recorder)
+	return cm, recorder
+}
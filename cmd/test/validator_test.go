@@ -0,0 +1,73 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"configure/internal/validator"
+)
+
+// TestValidators tests the validators for each kind of field.
+func TestValidators(t *testing.T) {
+	tests := []struct {
+		name     string
+		validate func(string) error
+		valid    []string
+		invalid  []string
+	}{
+		{
+			name:     "host address",
+			validate: validator.ValidateHostAddress,
+			valid:    []string{"8.8.8.8", "2001:4860:4860::8888", "fe80::1%eth0"},
+			invalid:  []string{"10.0.0.1/24", "bogus", "::ffff:8.8.8.8", "2001:db8::1%eth0", "0.0.0.0", "::", "224.0.0.1", "255.255.255.255"},
+		},
+		{
+			name:     "address with prefix",
+			validate: validator.ValidateAddressWithPrefix,
+			valid:    []string{"192.168.1.10/24", "2001:db8::1/64", "10.0.0.1/32"},
+			invalid:  []string{"192.168.1.10", "192.168.1.10/33", "2001:db8::1/129", "::ffff:10.0.0.1/120", "fe80::1%eth0/64", "224.0.0.1/4"},
+		},
+		{
+			name:     "prefix",
+			validate: validator.ValidatePrefix,
+			valid:    []string{"10.0.0.0/8", "2001:db8::/32", "0.0.0.0/0"},
+			invalid:  []string{"10.0.0.1/8", "10.0.0.0", "2001:db8::1/32"},
+		},
+		{
+			name:     "gateway",
+			validate: validator.ValidateGateway,
+			valid:    []string{"192.168.1.1", "2001:db8::1", "fe80::1%eth0"},
+			invalid:  []string{"192.168.1.1/24", "127.0.0.1", "::1", "ff02::1", "0.0.0.0"},
+		},
+		{
+			name:     "mtu",
+			validate: validator.ValidateMTU,
+			valid:    []string{"68", "1500", "9000", "16000"},
+			invalid:  []string{"67", "16001", "jumbo", ""},
+		},
+		{
+			name:     "speed",
+			validate: validator.ValidateSpeed,
+			valid:    []string{"auto", "100", "10000"},
+			invalid:  []string{"1G", "123", ""},
+		},
+		{
+			name:     "description",
+			validate: validator.ValidateDescription,
+			valid:    []string{"", "Uplink to core-1"},
+			invalid:  []string{"line\nbreak", strings.Repeat("x", 256)},
+		},
+	}
+	for _, tt := range tests {
+		for _, s := range tt.valid {
+			if err := tt.validate(s); err != nil {
+				t.Errorf("%s: expected %q to be valid, got %v", tt.name, s, err)
+			}
+		}
+		for _, s := range tt.invalid {
+			if err := tt.validate(s); err == nil {
+				t.Errorf("%s: expected %q to be invalid", tt.name, s)
+			}
+		}
+	}
+}
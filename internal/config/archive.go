@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultCommitRevisions is the number of commits kept in the commit archive
+// when system commit-revisions is not set
+const DefaultCommitRevisions = 100
+
+// MaxCommitRevisions is the largest accepted system commit-revisions value
+const MaxCommitRevisions = 65535
+
+// archiveDir is the name of the commit archive directory, stored next to the
+// running config file
+const archiveDir = "backup"
+
+// revisionFile matches the file names of archived commits
+var revisionFile = regexp.MustCompile(`^commit\.(\d+)\.yaml$`)
+
+// Revision is a committed configuration stored in the commit archive
+type Revision struct {
+	Seq     int       `yaml:"seq"`
+	User    string    `yaml:"user"`
+	Time    time.Time `yaml:"time"`
+	Comment string    `yaml:"comment,omitempty"`
+	Config  *Config   `yaml:"config"`
+}
+
+// archivePath returns the path of the commit archive directory
+func (cm *ConfigManager) archivePath() string {
+	return filepath.Join(filepath.Dir(cm.runningConfigPath), archiveDir)
+}
+
+// Archive stores the running configuration in the commit archive and prunes
+// revisions beyond the configured retention limit
+func (cm *ConfigManager) Archive(user, comment string) (*Revision, error) {
+	dir := cm.archivePath()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	seqs, err := cm.archivedSeqs()
+	if err != nil {
+		return nil, err
+	}
+	rev := &Revision{
+		Seq:     1,
+		User:    user,
+		Time:    time.Now(),
+		Comment: comment,
+		Config:  cm.Running.Clone(),
+	}
+	if len(seqs) > 0 {
+		rev.Seq = seqs[0] + 1
+	}
+
+	data, err := yaml.Marshal(rev)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal revision: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, revisionName(rev.Seq)), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write revision: %w", err)
+	}
+
+	limit := cm.Running.System.CommitRevisions
+	if limit <= 0 {
+		limit = DefaultCommitRevisions
+	}
+	// The new revision is not in seqs yet, so keep one less of the old ones
+	for i := limit - 1; i < len(seqs); i++ {
+		if err := os.Remove(filepath.Join(dir, revisionName(seqs[i]))); err != nil {
+			return nil, fmt.Errorf("failed to prune revision: %w", err)
+		}
+	}
+	return rev, nil
+}
+
+// Revisions returns all archived commits, newest first. The index of a
+// revision in the returned slice is its revision number.
+func (cm *ConfigManager) Revisions() ([]*Revision, error) {
+	seqs, err := cm.archivedSeqs()
+	if err != nil {
+		return nil, err
+	}
+
+	revs := make([]*Revision, 0, len(seqs))
+	for _, seq := range seqs {
+		rev, err := cm.readRevision(seq)
+		if err != nil {
+			return nil, err
+		}
+		revs = append(revs, rev)
+	}
+	return revs, nil
+}
+
+// Revision returns the archived commit with revision number n, where 0 is the newest
+func (cm *ConfigManager) Revision(n int) (*Revision, error) {
+	seqs, err := cm.archivedSeqs()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n >= len(seqs) {
+		return nil, fmt.Errorf("revision %d does not exist (archive has %d revisions)", n, len(seqs))
+	}
+	return cm.readRevision(seqs[n])
+}
+
+// archivedSeqs returns the sequence numbers of all archived commits, newest first
+func (cm *ConfigManager) archivedSeqs() ([]int, error) {
+	entries, err := os.ReadDir(cm.archivePath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive directory: %w", err)
+	}
+
+	var seqs []int
+	for _, e := range entries {
+		m := revisionFile.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		seq, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(seqs)))
+	return seqs, nil
+}
+
+// readRevision reads the archived commit with the given sequence number
+func (cm *ConfigManager) readRevision(seq int) (*Revision, error) {
+	data, err := os.ReadFile(filepath.Join(cm.archivePath(), revisionName(seq)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read revision: %w", err)
+	}
+
+	var rev Revision
+	if err := yaml.Unmarshal(data, &rev); err != nil {
+		return nil, fmt.Errorf("failed to parse revision %d: %w", seq, err)
+	}
+	if rev.Config == nil {
+		return nil, fmt.Errorf("revision %d has no configuration", seq)
+	}
+	rev.Config.normalize()
+	return &rev, nil
+}
+
+// revisionName returns the file name of the archived commit with the given sequence number
+func revisionName(seq int) string {
+	return fmt.Sprintf("commit.%d.yaml", seq)
+}
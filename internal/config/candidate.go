@@ -0,0 +1,77 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// candidateFile is the name of the file holding uncommitted changes, stored
+// next to the running config file. It lets the candidate configuration
+// outlive the process that edited it across one-shot commands.
+const candidateFile = ".nehv_candidate.yaml"
+
+// ErrStaleCandidate is returned by RestoreCandidate when the running configuration
+// has changed since the persisted candidate was saved
+var ErrStaleCandidate = errors.New("running configuration has changed since the uncommitted changes were saved")
+
+// persistedCandidate is the content of the candidate file
+type persistedCandidate struct {
+	// Base is the running configuration the changes were made against
+	Base      *Config `yaml:"base"`
+	Candidate *Config `yaml:"candidate"`
+}
+
+// candidatePath returns the path of the persisted candidate configuration
+func (cm *ConfigManager) candidatePath() string {
+	return filepath.Join(filepath.Dir(cm.runningConfigPath), candidateFile)
+}
+
+// RestoreCandidate replaces the candidate with the persisted one, if any. It
+// returns ErrStaleCandidate and keeps the candidate if the persisted changes
+// were made against a different running configuration, since committing them
+// would silently revert the commits made in the meantime.
+func (cm *ConfigManager) RestoreCandidate() error {
+	data, err := os.ReadFile(cm.candidatePath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read candidate config: %w", err)
+	}
+
+	p := persistedCandidate{Base: NewConfig(), Candidate: NewConfig()}
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return fmt.Errorf("failed to parse candidate config: %w", err)
+	}
+	p.Base.normalize()
+	p.Candidate.normalize()
+	if !p.Base.Equal(cm.Running) {
+		return ErrStaleCandidate
+	}
+	cm.Candidate = p.Candidate
+	return nil
+}
+
+// SaveCandidate persists the candidate configuration together with the running
+// configuration it is based on if it has uncommitted changes, and removes the
+// persisted candidate otherwise
+func (cm *ConfigManager) SaveCandidate() error {
+	if cm.Candidate.Equal(cm.Running) {
+		if err := os.Remove(cm.candidatePath()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove candidate config: %w", err)
+		}
+		return nil
+	}
+	data, err := yaml.Marshal(persistedCandidate{Base: cm.Running, Candidate: cm.Candidate})
+	if err != nil {
+		return fmt.Errorf("failed to marshal candidate config: %w", err)
+	}
+	if err := os.WriteFile(cm.candidatePath(), data, 0600); err != nil {
+		return fmt.Errorf("failed to write candidate config: %w", err)
+	}
+	return nil
+}
@@ -1,10 +1,10 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
-	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -15,19 +15,119 @@ type Config struct {
 	Interfaces   map[string]InterfaceConfig `yaml:"interfaces"`
 	DNS          []string                   `yaml:"dns"`
 	DefaultRoute string                     `yaml:"default_route"`
+	Protocols    ProtocolsConfig            `yaml:"protocols,omitempty"`
+	System       SystemConfig               `yaml:"system,omitempty"`
 }
 
+// SystemConfig represents settings of the configuration system itself
+type SystemConfig struct {
+	// CommitRevisions is the number of commits kept in the commit archive
+	CommitRevisions int `yaml:"commit_revisions,omitempty"`
+}
+
+// Address values that request dynamic addressing instead of a static address
+const (
+	AddressDHCP   = "dhcp"
+	AddressDHCPv6 = "dhcpv6"
+)
+
 // InterfaceConfig represents network interface configuration
 type InterfaceConfig struct {
-	Address string `yaml:"address"`
-	MAC     string `yaml:"mac,omitempty"`
+	// Addresses are static addresses with prefix length, AddressDHCP or AddressDHCPv6
+	Addresses AddressList `yaml:"address,omitempty"`
+	MAC       string      `yaml:"mac,omitempty"`
+	// Virtual declares an interface that does not have to exist on the system
+	// when it is configured, e.g. one created later by other tooling
+	Virtual bool       `yaml:"virtual,omitempty"`
+	IPv6    IPv6Config `yaml:"ipv6,omitempty"`
+	// Description is stored as the interface alias
+	Description string `yaml:"description,omitempty"`
+	// MTU is the maximum transmission unit; zero leaves the MTU of the link
+	MTU int `yaml:"mtu,omitempty"`
+	// Disable sets the interface administratively down
+	Disable bool `yaml:"disable,omitempty"`
+	// Speed is the ethernet link speed in Mbit/s or "auto"
+	Speed string `yaml:"speed,omitempty"`
+	// Duplex is the ethernet duplex mode: "auto", "half" or "full"
+	Duplex string `yaml:"duplex,omitempty"`
+	// Offload lists the enabled ethtool offload features
+	Offload []string `yaml:"offload,omitempty"`
+}
+
+// DefaultMTU is the MTU restored when an MTU is no longer configured on an
+// interface whose previous MTU is not known
+const DefaultMTU = 1500
+
+// LinkAuto is the speed and duplex value that leaves them to autonegotiation
+const LinkAuto = "auto"
+
+// IPv6Config represents IPv6 settings of an interface
+type IPv6Config struct {
+	// Autoconf enables stateless address autoconfiguration (SLAAC)
+	Autoconf bool `yaml:"autoconf,omitempty"`
+}
+
+// IsEmpty reports whether nothing is configured on the interface
+func (i InterfaceConfig) IsEmpty() bool {
+	return len(i.Addresses) == 0 && i.MAC == "" && !i.Virtual && i.IPv6 == IPv6Config{} &&
+		i.Description == "" && i.MTU == 0 && !i.Disable && i.Speed == "" && i.Duplex == "" &&
+		len(i.Offload) == 0
+}
+
+// HasAddress reports whether an address is configured on the interface
+func (i InterfaceConfig) HasAddress(addr string) bool {
+	return contains(i.Addresses, addr)
+}
+
+// LinkMode returns the configured speed and duplex, with unset values as LinkAuto
+func (i InterfaceConfig) LinkMode() (speed, duplex string) {
+	speed, duplex = i.Speed, i.Duplex
+	if speed == "" {
+		speed = LinkAuto
+	}
+	if duplex == "" {
+		duplex = LinkAuto
+	}
+	return speed, duplex
 }
 
-// ConfigManager handles configuration operations
+// HasOffload reports whether an offload feature is enabled on the interface
+func (i InterfaceConfig) HasOffload(feature string) bool {
+	return contains(i.Offload, feature)
+}
+
+// AddressList is the list of addresses of an interface. When parsing, it also
+// accepts the single address string of older configuration files.
+type AddressList []string
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (l *AddressList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = nil
+		if value.Value != "" {
+			*l = AddressList{value.Value}
+		}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// ConfigManager handles configuration operations.
+//
+// It keeps two in-memory views of the configuration: the candidate, which is
+// edited by set commands, and the running configuration, which only changes on
+// a successful commit. The boot configuration lives on disk and is only
+// written by Save.
 type ConfigManager struct {
 	bootConfigPath    string
 	runningConfigPath string
-	Config            *Config
+	Candidate         *Config
+	Running           *Config
 }
 
 // NewConfigManager creates a new ConfigManager instance
@@ -35,114 +135,367 @@ func NewConfigManager(bootPath, runningPath string) *ConfigManager {
 	return &ConfigManager{
 		bootConfigPath:    bootPath,
 		runningConfigPath: runningPath,
-		Config: &Config{
-			Hostname:   "vyos-router",
-			Interfaces: make(map[string]InterfaceConfig),
-			DNS:        make([]string, 0),
-		},
+		Candidate:         NewConfig(),
+		Running:           NewConfig(),
 	}
 }
 
-// Load loads the configuration from the boot config file
-func (cm *ConfigManager) Load() error {
-	data, err := os.ReadFile(cm.bootConfigPath)
+// NewConfig returns the default configuration
+func NewConfig() *Config {
+	return &Config{
+		Hostname:   "vyos-router",
+		Interfaces: make(map[string]InterfaceConfig),
+		DNS:        make([]string, 0),
+	}
+}
+
+// NewEmptyConfig returns a configuration without any settings
+func NewEmptyConfig() *Config {
+	return &Config{
+		Interfaces: make(map[string]InterfaceConfig),
+		DNS:        make([]string, 0),
+	}
+}
+
+// Clone returns a deep copy of the configuration
+func (c *Config) Clone() *Config {
+	clone := &Config{
+		Hostname:     c.Hostname,
+		Interfaces:   make(map[string]InterfaceConfig, len(c.Interfaces)),
+		DNS:          append(make([]string, 0, len(c.DNS)), c.DNS...),
+		DefaultRoute: c.DefaultRoute,
+		Protocols:    ProtocolsConfig{Static: StaticConfig{Routes: cloneRoutes(c.Protocols.Static.Routes)}},
+		System:       c.System,
+	}
+	for name, iface := range c.Interfaces {
+		iface.Addresses = append(AddressList(nil), iface.Addresses...)
+		iface.Offload = append([]string(nil), iface.Offload...)
+		clone.Interfaces[name] = iface
+	}
+	return clone
+}
+
+// Equal reports whether two configurations are identical
+func (c *Config) Equal(other *Config) bool {
+	a, errA := yaml.Marshal(c)
+	b, errB := yaml.Marshal(other)
+	return errA == nil && errB == nil && string(a) == string(b)
+}
+
+// LoadConfig reads a configuration file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			// Create default config if it doesn't exist
-			return cm.Save()
+		return nil, err
+	}
+
+	cfg := NewConfig()
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	cfg.normalize()
+	return cfg, nil
+}
+
+// ParseConfig parses a configuration file given by an operator. Unlike
+// LoadConfig it rejects unknown fields and leaves missing settings unset.
+func ParseConfig(data []byte) (*Config, error) {
+	cfg := NewEmptyConfig()
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	cfg.normalize()
+	return cfg, nil
+}
+
+// Merge overlays other onto the configuration: settings set in other replace
+// existing ones, interfaces and static routes are merged by name and prefix,
+// and DNS servers, interface addresses and offload features are unioned
+func (c *Config) Merge(other *Config) {
+	if other.Hostname != "" {
+		c.Hostname = other.Hostname
+	}
+	for name, o := range other.Interfaces {
+		iface := c.Interfaces[name]
+		for _, addr := range o.Addresses {
+			if !iface.HasAddress(addr) {
+				iface.Addresses = append(iface.Addresses, addr)
+			}
 		}
-		return fmt.Errorf("failed to read config file: %w", err)
+		if o.MAC != "" {
+			iface.MAC = o.MAC
+		}
+		if o.Virtual {
+			iface.Virtual = true
+		}
+		if o.IPv6.Autoconf {
+			iface.IPv6.Autoconf = true
+		}
+		if o.Description != "" {
+			iface.Description = o.Description
+		}
+		if o.MTU != 0 {
+			iface.MTU = o.MTU
+		}
+		if o.Disable {
+			iface.Disable = true
+		}
+		if o.Speed != "" {
+			iface.Speed = o.Speed
+		}
+		if o.Duplex != "" {
+			iface.Duplex = o.Duplex
+		}
+		for _, feature := range o.Offload {
+			if !iface.HasOffload(feature) {
+				iface.Offload = append(iface.Offload, feature)
+			}
+		}
+		c.Interfaces[name] = iface
+	}
+	for _, server := range other.DNS {
+		if !contains(c.DNS, server) {
+			c.DNS = append(c.DNS, server)
+		}
+	}
+	if other.DefaultRoute != "" {
+		c.DefaultRoute = other.DefaultRoute
+	}
+	for prefix, o := range other.Protocols.Static.Routes {
+		if c.Protocols.Static.Routes == nil {
+			c.Protocols.Static.Routes = make(map[string]StaticRoute)
+		}
+		r := c.Protocols.Static.Routes[prefix].clone()
+		r.merge(o)
+		c.Protocols.Static.Routes[prefix] = r
 	}
+	if other.System.CommitRevisions != 0 {
+		c.System.CommitRevisions = other.System.CommitRevisions
+	}
+}
+
+// normalize replaces nil collections left by parsing with empty ones and drops
+// interfaces without any settings, which have no command representation
+func (c *Config) normalize() {
+	if c.Interfaces == nil {
+		c.Interfaces = make(map[string]InterfaceConfig)
+	}
+	for name, iface := range c.Interfaces {
+		if iface.IsEmpty() {
+			delete(c.Interfaces, name)
+		}
+	}
+	if c.DNS == nil {
+		c.DNS = make([]string, 0)
+	}
+}
 
-	if err := yaml.Unmarshal(data, cm.Config); err != nil {
-		return fmt.Errorf("failed to parse config file: %w", err)
+// SaveConfig writes a configuration file
+func SaveConfig(cfg *Config, path string) error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
 	}
+	return os.WriteFile(path, data, 0644)
+}
 
+// Load loads the running configuration and resets the candidate to it.
+// If no running configuration exists yet, the boot configuration is used,
+// and if neither exists a default configuration is written to both files.
+func (cm *ConfigManager) Load() error {
+	cfg, err := cm.readConfig()
+	if os.IsNotExist(err) {
+		// Create default config if it doesn't exist
+		cm.Running = NewConfig()
+		cm.Candidate = cm.Running.Clone()
+		if err := cm.Commit(); err != nil {
+			return err
+		}
+		return cm.Save()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cm.Running = cfg
+	cm.Candidate = cfg.Clone()
 	return nil
 }
 
-// Save saves the current configuration to both boot and running config files
-func (cm *ConfigManager) Save() error {
-	data, err := yaml.Marshal(cm.Config)
+// LoadReadOnly loads the configuration like Load, but never writes a file.
+// If neither config file can be read, the default configuration is used.
+func (cm *ConfigManager) LoadReadOnly() {
+	cfg, err := cm.readConfig()
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		cfg = NewConfig()
 	}
+	cm.Running = cfg
+	cm.Candidate = cfg.Clone()
+}
 
-	// Save to boot config
-	if err := os.WriteFile(cm.bootConfigPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write boot config: %w", err)
+// readConfig reads the running config file, or the boot config file if there
+// is no running configuration yet
+func (cm *ConfigManager) readConfig() (*Config, error) {
+	cfg, err := LoadConfig(cm.runningConfigPath)
+	if os.IsNotExist(err) {
+		cfg, err = LoadConfig(cm.bootConfigPath)
 	}
+	return cfg, err
+}
 
-	// Save to running config
-	if err := os.WriteFile(cm.runningConfigPath, data, 0644); err != nil {
+// Commit promotes the candidate configuration to the running configuration
+// and writes it to the running config file
+func (cm *ConfigManager) Commit() error {
+	return cm.SetRunning(cm.Candidate)
+}
+
+// SetRunning replaces the running configuration and writes it to the running
+// config file, leaving the candidate untouched
+func (cm *ConfigManager) SetRunning(cfg *Config) error {
+	running := cfg.Clone()
+	if err := SaveConfig(running, cm.runningConfigPath); err != nil {
 		return fmt.Errorf("failed to write running config: %w", err)
 	}
+	cm.Running = running
+	return nil
+}
+
+// ReloadRunning re-reads the running configuration from the running config file
+// and reports whether it differs from the in-memory copy
+func (cm *ConfigManager) ReloadRunning() (bool, error) {
+	running, err := cm.ReadRunningConfig()
+	if err != nil {
+		return false, err
+	}
+	changed := !running.Equal(cm.Running)
+	cm.Running = running
+	return changed, nil
+}
 
+// ResetCandidate replaces the candidate configuration with a copy of the running configuration
+func (cm *ConfigManager) ResetCandidate() {
+	cm.Candidate = cm.Running.Clone()
+}
+
+// Save saves the running configuration to the boot config file
+func (cm *ConfigManager) Save() error {
+	if err := SaveConfig(cm.Running, cm.bootConfigPath); err != nil {
+		return fmt.Errorf("failed to write boot config: %w", err)
+	}
 	return nil
 }
 
-// GetConfig returns the current configuration
+// Saved reports whether the boot config file matches the running configuration
+func (cm *ConfigManager) Saved() (bool, error) {
+	boot, err := LoadConfig(cm.bootConfigPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read boot config: %w", err)
+	}
+	return boot.Equal(cm.Running), nil
+}
+
+// GetConfig returns the candidate configuration
 func (cm *ConfigManager) GetConfig() *Config {
-	return cm.Config
+	return cm.Candidate
+}
+
+// GetRunningConfig returns the running configuration
+func (cm *ConfigManager) GetRunningConfig() *Config {
+	return cm.Running
+}
+
+// ReadRunningConfig reads the running configuration from the running config file.
+// If the file does not exist yet, the in-memory running configuration is returned.
+func (cm *ConfigManager) ReadRunningConfig() (*Config, error) {
+	cfg, err := LoadConfig(cm.runningConfigPath)
+	if os.IsNotExist(err) {
+		return cm.Running.Clone(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read running config: %w", err)
+	}
+	return cfg, nil
+}
+
+// SetHostname sets the host name
+func (cm *ConfigManager) SetHostname(name string) {
+	cm.Candidate.Hostname = name
 }
 
 // SetDNS sets the DNS servers
 func (cm *ConfigManager) SetDNS(servers []string) {
-	cm.Config.DNS = servers
+	cm.Candidate.DNS = servers
 }
 
 // AddDNS adds a DNS server
 func (cm *ConfigManager) AddDNS(server string) {
-	for _, existing := range cm.Config.DNS {
+	for _, existing := range cm.Candidate.DNS {
 		if existing == server {
 			return
 		}
 	}
-	cm.Config.DNS = append(cm.Config.DNS, server)
+	cm.Candidate.DNS = append(cm.Candidate.DNS, server)
 }
 
-// SetInterface sets interface configuration
+// SetInterface sets interface configuration, removing the interface once
+// nothing is configured on it
 func (cm *ConfigManager) SetInterface(name string, iface InterfaceConfig) {
-	cm.Config.Interfaces[name] = iface
+	if iface.IsEmpty() {
+		delete(cm.Candidate.Interfaces, name)
+		return
+	}
+	cm.Candidate.Interfaces[name] = iface
 }
 
 // SetDefaultRoute sets the default route
 func (cm *ConfigManager) SetDefaultRoute(route string) {
-	cm.Config.DefaultRoute = route
+	cm.Candidate.DefaultRoute = route
 }
 
-// Backup creates a backup of the current configuration
-func (cm *ConfigManager) Backup() error {
-	backupDir := "backup"
-	if err := os.MkdirAll(backupDir, 0755); err != nil {
-		return fmt.Errorf("failed to create backup directory: %w", err)
+// DeleteDNS removes a DNS server, or all DNS servers if server is empty
+func (cm *ConfigManager) DeleteDNS(server string) error {
+	if server == "" {
+		cm.Candidate.DNS = make([]string, 0)
+		return nil
 	}
-
-	backupPath := filepath.Join(backupDir, fmt.Sprintf("config_%s.yaml", time.Now().Format("20060102_150405")))
-	data, err := yaml.Marshal(cm.Config)
-	if err != nil {
-		return fmt.Errorf("failed to marshal config for backup: %w", err)
+	for i, existing := range cm.Candidate.DNS {
+		if existing == server {
+			cm.Candidate.DNS = append(cm.Candidate.DNS[:i:i], cm.Candidate.DNS[i+1:]...)
+			return nil
+		}
 	}
+	return fmt.Errorf("DNS server %s is not configured", server)
+}
 
-	if err := os.WriteFile(backupPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write backup file: %w", err)
+// DeleteInterface removes the configuration of an interface
+func (cm *ConfigManager) DeleteInterface(name string) error {
+	if _, ok := cm.Candidate.Interfaces[name]; !ok {
+		return fmt.Errorf("interface %s is not configured", name)
 	}
-
+	delete(cm.Candidate.Interfaces, name)
 	return nil
 }
 
-// Restore restores configuration from a backup file
-func (cm *ConfigManager) Restore(backupPath string) error {
-	data, err := os.ReadFile(backupPath)
-	if err != nil {
-		return fmt.Errorf("failed to read backup file: %w", err)
+// DeleteDefaultRoute removes the default route
+func (cm *ConfigManager) DeleteDefaultRoute() error {
+	if cm.Candidate.DefaultRoute == "" {
+		return fmt.Errorf("default route is not configured")
 	}
+	cm.Candidate.DefaultRoute = ""
+	return nil
+}
 
-	var backupConfig Config
-	if err := yaml.Unmarshal(data, &backupConfig); err != nil {
-		return fmt.Errorf("failed to parse backup file: %w", err)
-	}
+// SetCommitRevisions sets the number of commits kept in the commit archive
+func (cm *ConfigManager) SetCommitRevisions(n int) {
+	cm.Candidate.System.CommitRevisions = n
+}
 
-	cm.Config = &backupConfig
-	return cm.Save()
+// LoadCandidate replaces the candidate configuration with a copy of cfg
+func (cm *ConfigManager) LoadCandidate(cfg *Config) {
+	cm.Candidate = cfg.Clone()
 }
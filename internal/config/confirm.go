@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// pendingRollbackFile is the name of the commit-confirm record, stored next to
+// the running config file
+const pendingRollbackFile = ".nehv_commit_confirm.yaml"
+
+// PendingRollback records a commit-confirm that has not been confirmed yet.
+// It is persisted so that the rollback happens even if the CLI process exits.
+type PendingRollback struct {
+	ID       string    `yaml:"id"`
+	Deadline time.Time `yaml:"deadline"`
+	Previous *Config   `yaml:"previous"`
+}
+
+// pendingRollbackPath returns the path of the commit-confirm record
+func (cm *ConfigManager) pendingRollbackPath() string {
+	return filepath.Join(filepath.Dir(cm.runningConfigPath), pendingRollbackFile)
+}
+
+// PendingRollback returns the pending commit-confirm record, or nil if there is none
+func (cm *ConfigManager) PendingRollback() (*PendingRollback, error) {
+	p, err := readPendingRollback(cm.pendingRollbackPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// readPendingRollback reads a commit-confirm record from path
+func readPendingRollback(path string) (*PendingRollback, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pending rollback: %w", err)
+	}
+
+	var p PendingRollback
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse pending rollback: %w", err)
+	}
+	if p.Previous == nil {
+		return nil, fmt.Errorf("pending rollback %s has no previous configuration", p.ID)
+	}
+	p.Previous.normalize()
+	return &p, nil
+}
+
+// SetPendingRollback persists a pending commit-confirm record, replacing any existing one
+func (cm *ConfigManager) SetPendingRollback(p *PendingRollback) error {
+	data, err := yaml.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pending rollback: %w", err)
+	}
+
+	// Write to a temporary file first so a concurrent reader never sees a partial record
+	tmp := cm.pendingRollbackPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write pending rollback: %w", err)
+	}
+	if err := os.Rename(tmp, cm.pendingRollbackPath()); err != nil {
+		return fmt.Errorf("failed to write pending rollback: %w", err)
+	}
+	return nil
+}
+
+// ClaimPendingRollback removes the pending commit-confirm record with the given ID
+// and returns it. It returns nil if there is no such record, for example because it
+// was already confirmed or rolled back by another process.
+func (cm *ConfigManager) ClaimPendingRollback(id string) (*PendingRollback, error) {
+	// Renaming is atomic, so only one process can claim the record. The ID is
+	// checked afterwards, as the record may be replaced right up to the rename.
+	path := cm.pendingRollbackPath()
+	claimed := fmt.Sprintf("%s.%d", path, os.Getpid())
+	if err := os.Rename(path, claimed); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim pending rollback: %w", err)
+	}
+	defer os.Remove(claimed)
+
+	p, err := readPendingRollback(claimed)
+	if err == nil && p.ID == id {
+		return p, nil
+	}
+
+	// Not ours: put it back, unless a newer record has been written meanwhile
+	if err := os.Link(claimed, path); err != nil && !os.IsExist(err) {
+		return nil, fmt.Errorf("failed to restore pending rollback: %w", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
@@ -0,0 +1,501 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"configure/internal/lexer"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ChangeKind describes how a configuration node differs between two configurations
+type ChangeKind int
+
+const (
+	// Added means the node only exists in the new configuration
+	Added ChangeKind = iota
+	// Removed means the node only exists in the old configuration
+	Removed
+	// Changed means the node exists in both configurations with different values
+	Changed
+)
+
+// String returns the diff marker for the change kind
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "+"
+	case Removed:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// Change represents a single difference between two configurations
+type Change struct {
+	Kind ChangeKind
+	Path []string
+	Old  string
+	New  string
+}
+
+// Diff returns the changes needed to turn old into new.
+// Interfaces are compared by name, DNS servers as an ordered list and static
+// routes by prefix and target.
+func Diff(old, new *Config) []Change {
+	var changes []Change
+
+	if old.Hostname != new.Hostname {
+		changes = append(changes, valueChange([]string{"hostname"}, old.Hostname, new.Hostname))
+	}
+
+	names := make(map[string]bool)
+	for name := range old.Interfaces {
+		names[name] = true
+	}
+	for name := range new.Interfaces {
+		names[name] = true
+	}
+	for _, name := range sortedKeys(names) {
+		oldIface, inOld := old.Interfaces[name]
+		newIface, inNew := new.Interfaces[name]
+		path := []string{"interfaces", name}
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Kind: Added, Path: path})
+		case !inNew:
+			changes = append(changes, Change{Kind: Removed, Path: path})
+		}
+		// virtual comes first so the set commands replay in a valid order
+		if oldIface.Virtual != newIface.Virtual {
+			changes = append(changes, valueChange(append(path, "virtual"), flagString(oldIface.Virtual), flagString(newIface.Virtual)))
+		}
+		addrPath := append(path[:len(path):len(path)], "address")
+		for _, addr := range oldIface.Addresses {
+			if !newIface.HasAddress(addr) {
+				changes = append(changes, Change{Kind: Removed, Path: addrPath, Old: addr})
+			}
+		}
+		for _, addr := range newIface.Addresses {
+			if !oldIface.HasAddress(addr) {
+				changes = append(changes, Change{Kind: Added, Path: addrPath, New: addr})
+			}
+		}
+		if oldIface.MAC != newIface.MAC {
+			changes = append(changes, valueChange(append(path, "mac"), oldIface.MAC, newIface.MAC))
+		}
+		if oldIface.IPv6.Autoconf != newIface.IPv6.Autoconf {
+			changes = append(changes, valueChange(append(path, "ipv6", "address", "autoconf"), flagString(oldIface.IPv6.Autoconf), flagString(newIface.IPv6.Autoconf)))
+		}
+		if oldIface.Description != newIface.Description {
+			changes = append(changes, valueChange(append(path, "description"), oldIface.Description, newIface.Description))
+		}
+		if oldIface.MTU != newIface.MTU {
+			changes = append(changes, valueChange(append(path, "mtu"), numberString(oldIface.MTU), numberString(newIface.MTU)))
+		}
+		if oldIface.Speed != newIface.Speed {
+			changes = append(changes, valueChange(append(path, "speed"), oldIface.Speed, newIface.Speed))
+		}
+		if oldIface.Duplex != newIface.Duplex {
+			changes = append(changes, valueChange(append(path, "duplex"), oldIface.Duplex, newIface.Duplex))
+		}
+		offloadPath := append(path[:len(path):len(path)], "offload")
+		for _, feature := range oldIface.Offload {
+			if !newIface.HasOffload(feature) {
+				changes = append(changes, Change{Kind: Removed, Path: offloadPath, Old: feature})
+			}
+		}
+		for _, feature := range newIface.Offload {
+			if !oldIface.HasOffload(feature) {
+				changes = append(changes, Change{Kind: Added, Path: offloadPath, New: feature})
+			}
+		}
+		if oldIface.Disable != newIface.Disable {
+			changes = append(changes, valueChange(append(path, "disable"), flagString(oldIface.Disable), flagString(newIface.Disable)))
+		}
+	}
+
+	// add dns appends, so everything after the common prefix is removed
+	// and added again in the new order
+	common := 0
+	for common < len(old.DNS) && common < len(new.DNS) && old.DNS[common] == new.DNS[common] {
+		common++
+	}
+	for _, server := range old.DNS[common:] {
+		changes = append(changes, Change{Kind: Removed, Path: []string{"dns"}, Old: server})
+	}
+	for _, server := range new.DNS[common:] {
+		changes = append(changes, Change{Kind: Added, Path: []string{"dns"}, New: server})
+	}
+
+	if old.DefaultRoute != new.DefaultRoute {
+		changes = append(changes, valueChange([]string{"default_route"}, old.DefaultRoute, new.DefaultRoute))
+	}
+
+	changes = append(changes, diffStaticRoutes(old.Protocols.Static.Routes, new.Protocols.Static.Routes)...)
+
+	if old.System.CommitRevisions != new.System.CommitRevisions {
+		changes = append(changes, valueChange([]string{"system", "commit-revisions"}, numberString(old.System.CommitRevisions), numberString(new.System.CommitRevisions)))
+	}
+
+	return changes
+}
+
+// diffStaticRoutes returns the changes between two sets of static routes. A
+// removed route is a single change; an added route is followed by its targets.
+func diffStaticRoutes(old, new map[string]StaticRoute) []Change {
+	var changes []Change
+	prefixes := make(map[string]bool)
+	for prefix := range old {
+		prefixes[prefix] = true
+	}
+	for prefix := range new {
+		prefixes[prefix] = true
+	}
+	for _, prefix := range sortedKeys(prefixes) {
+		oldRoute, inOld := old[prefix]
+		newRoute, inNew := new[prefix]
+		path := []string{"protocols", "static", "route", prefix}
+		switch {
+		case !inNew:
+			changes = append(changes, Change{Kind: Removed, Path: path})
+			continue
+		case !inOld:
+			changes = append(changes, Change{Kind: Added, Path: path})
+		}
+		changes = append(changes, diffTargets(append(path[:len(path):len(path)], "next-hop"), oldRoute.NextHops, newRoute.NextHops)...)
+		changes = append(changes, diffTargets(append(path[:len(path):len(path)], "interface"), oldRoute.Interfaces, newRoute.Interfaces)...)
+		blackholePath := append(path[:len(path):len(path)], "blackhole")
+		switch {
+		case oldRoute.Blackhole != nil && newRoute.Blackhole == nil:
+			changes = append(changes, Change{Kind: Removed, Path: blackholePath})
+		case newRoute.Blackhole != nil:
+			changes = append(changes, diffTarget(blackholePath, oldRoute.Blackhole, *newRoute.Blackhole)...)
+		}
+	}
+	return changes
+}
+
+// diffTargets returns the changes between two sets of route targets
+func diffTargets(path []string, old, new map[string]RouteTarget) []Change {
+	var changes []Change
+	for _, key := range sortedKeys(old) {
+		if _, ok := new[key]; !ok {
+			changes = append(changes, Change{Kind: Removed, Path: append(path[:len(path):len(path)], key)})
+		}
+	}
+	for _, key := range sortedKeys(new) {
+		var oldTarget *RouteTarget
+		if t, ok := old[key]; ok {
+			oldTarget = &t
+		}
+		changes = append(changes, diffTarget(append(path[:len(path):len(path)], key), oldTarget, new[key])...)
+	}
+	return changes
+}
+
+// diffTarget returns the changes of a route target that exists in the new
+// configuration; old is nil if the target is new
+func diffTarget(path []string, old *RouteTarget, new RouteTarget) []Change {
+	var changes []Change
+	oldDistance := 0
+	if old == nil {
+		changes = append(changes, Change{Kind: Added, Path: path})
+	} else {
+		oldDistance = old.Distance
+	}
+	if oldDistance != new.Distance {
+		changes = append(changes, valueChange(append(path[:len(path):len(path)], "distance"), numberString(oldDistance), numberString(new.Distance)))
+	}
+	return changes
+}
+
+// CompareCommands renders changes as the set/add/delete commands that
+// turn the old configuration into the new one
+func CompareCommands(changes []Change) []string {
+	var cmds []string
+	for _, c := range changes {
+		switch {
+		case len(c.Path) == 2 && c.Path[0] == "interfaces":
+			// Whole interface additions carry no value of their own; their
+			// address and mac changes follow as separate entries
+			if c.Kind == Removed {
+				cmds = append(cmds, "delete interfaces "+c.Path[1])
+			}
+		case c.Path[0] == "interfaces" && c.Kind == Removed && interfaceRemoved(changes, c.Path[1]):
+			// Covered by the deletion of the whole interface
+		case c.Path[0] == "interfaces" && isList(c.Path) && c.Kind == Removed:
+			cmds = append(cmds, "delete "+strings.Join(c.Path, " ")+" "+lexer.Quote(c.Old))
+		case c.Path[0] == "interfaces" && c.Kind == Removed:
+			cmds = append(cmds, "delete "+strings.Join(c.Path, " "))
+		case c.Path[0] == "interfaces" && isFlag(c.Path):
+			cmds = append(cmds, "set "+strings.Join(c.Path, " "))
+		case c.Path[0] == "interfaces":
+			cmds = append(cmds, "set "+strings.Join(c.Path, " ")+" "+lexer.Quote(c.New))
+		case c.Path[0] == "dns" && c.Kind == Removed:
+			cmds = append(cmds, "delete dns "+lexer.Quote(c.Old))
+		case c.Path[0] == "dns":
+			cmds = append(cmds, "add dns "+lexer.Quote(c.New))
+		case c.Path[0] == "default_route" && c.Kind == Removed:
+			cmds = append(cmds, "delete ip route default")
+		case c.Path[0] == "default_route":
+			cmds = append(cmds, "set ip route default via "+lexer.Quote(c.New))
+		case c.Path[0] == "protocols" && len(c.Path) == 4 && c.Kind == Added:
+			// Like interfaces, a new route is set through its targets
+		case c.Path[0] == "protocols" && c.Kind == Removed:
+			cmds = append(cmds, "delete "+quotePath(c.Path))
+		case c.Path[0] == "protocols" && c.New == "":
+			cmds = append(cmds, "set "+quotePath(c.Path))
+		case c.Path[0] == "protocols":
+			cmds = append(cmds, "set "+quotePath(c.Path)+" "+lexer.Quote(c.New))
+		case c.Kind == Removed:
+			cmds = append(cmds, "delete "+strings.Join(c.Path, " "))
+		default:
+			cmds = append(cmds, "set "+strings.Join(c.Path, " ")+" "+lexer.Quote(c.New))
+		}
+	}
+	return cmds
+}
+
+// Commands renders a configuration as the set/add commands that recreate it
+// from an empty configuration
+func Commands(cfg *Config) []string {
+	return CompareCommands(Diff(NewEmptyConfig(), cfg))
+}
+
+// UnifiedDiff renders the YAML form of two configurations as a unified diff.
+// It returns an empty string if both render identically.
+func UnifiedDiff(oldName, newName string, old, new *Config) (string, error) {
+	oldData, err := yaml.Marshal(old)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal config: %w", err)
+	}
+	newData, err := yaml.Marshal(new)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return unified(oldName, newName, splitLines(string(oldData)), splitLines(string(newData))), nil
+}
+
+// contextLines is the number of unchanged lines shown around each hunk
+const contextLines = 3
+
+// diffLine is a single line of an edit script
+type diffLine struct {
+	op   byte // ' ', '-' or '+'
+	text string
+}
+
+// unified formats the line-level edit script between a and b as unified diff hunks
+func unified(oldName, newName string, a, b []string) string {
+	script := editScript(a, b)
+
+	changed := false
+	for _, l := range script {
+		if l.op != ' ' {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+
+	// Group the script into hunks of changes separated by more than
+	// 2*contextLines unchanged lines
+	for i := 0; i < len(script); {
+		if script[i].op == ' ' {
+			i++
+			continue
+		}
+		start := max(i-contextLines, 0)
+		end := i
+		for end < len(script) {
+			if script[end].op != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(script) && script[next].op == ' ' {
+				next++
+			}
+			if next == len(script) || next-end > 2*contextLines {
+				break
+			}
+			end = next
+		}
+		end = min(end+contextLines, len(script))
+
+		oldStart, newStart := 1, 1
+		for _, l := range script[:start] {
+			if l.op != '+' {
+				oldStart++
+			}
+			if l.op != '-' {
+				newStart++
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, l := range script[start:end] {
+			if l.op != '+' {
+				oldCount++
+			}
+			if l.op != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+		for _, l := range script[start:end] {
+			fmt.Fprintf(&sb, "%c%s\n", l.op, l.text)
+		}
+		i = end
+	}
+	return sb.String()
+}
+
+// editScript computes a minimal line edit script using the longest common subsequence
+func editScript(a, b []string) []diffLine {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var script []diffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			script = append(script, diffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			script = append(script, diffLine{'-', a[i]})
+			i++
+		default:
+			script = append(script, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		script = append(script, diffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		script = append(script, diffLine{'+', b[j]})
+	}
+	return script
+}
+
+// hunkRange formats a unified diff line range
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits text into lines without trailing newline characters
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// valueChange returns the change for a scalar value that differs
+func valueChange(path []string, old, new string) Change {
+	switch {
+	case old == "":
+		return Change{Kind: Added, Path: path, New: new}
+	case new == "":
+		return Change{Kind: Removed, Path: path, Old: old}
+	default:
+		return Change{Kind: Changed, Path: path, Old: old, New: new}
+	}
+}
+
+// quotePath joins the elements of a path, quoting them where needed
+func quotePath(path []string) string {
+	quoted := make([]string, len(path))
+	for i, elem := range path {
+		quoted[i] = lexer.Quote(elem)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// isFlag reports whether a path names a setting without value
+func isFlag(path []string) bool {
+	last := path[len(path)-1]
+	return last == "virtual" || last == "autoconf" || last == "disable"
+}
+
+// isList reports whether a path names an interface setting with several
+// values, whose entries are deleted one by one
+func isList(path []string) bool {
+	return len(path) == 3 && (path[2] == "address" || path[2] == "offload")
+}
+
+// flagString formats a boolean flag, with false meaning unset
+func flagString(b bool) string {
+	if b {
+		return "true"
+	}
+	return ""
+}
+
+// numberString formats a number, with zero meaning unset
+func numberString(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// interfaceRemoved reports whether changes contain the removal of the whole interface
+func interfaceRemoved(changes []Change, name string) bool {
+	for _, c := range changes {
+		if c.Kind == Removed && len(c.Path) == 2 && c.Path[0] == "interfaces" && c.Path[1] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether list contains s
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// originalLinkFile is the name of the file recording the settings links had
+// before they were configured, stored next to the running config file
+const originalLinkFile = ".nehv_original_link.yaml"
+
+// OriginalLinks are the settings links had before they were first configured,
+// by link name, so that removing the configuration restores them
+type OriginalLinks struct {
+	MTUs map[string]int    `yaml:"mtu,omitempty"`
+	MACs map[string]string `yaml:"mac,omitempty"`
+}
+
+// Clone returns a deep copy of the original link settings with non-nil maps
+func (o OriginalLinks) Clone() OriginalLinks {
+	clone := OriginalLinks{
+		MTUs: make(map[string]int, len(o.MTUs)),
+		MACs: make(map[string]string, len(o.MACs)),
+	}
+	for link, mtu := range o.MTUs {
+		clone.MTUs[link] = mtu
+	}
+	for link, mac := range o.MACs {
+		clone.MACs[link] = mac
+	}
+	return clone
+}
+
+// originalLinkPath returns the path of the original link record
+func (cm *ConfigManager) originalLinkPath() string {
+	return filepath.Join(filepath.Dir(cm.runningConfigPath), originalLinkFile)
+}
+
+// OriginalLinks returns the settings links had before they were configured
+func (cm *ConfigManager) OriginalLinks() (OriginalLinks, error) {
+	var links OriginalLinks
+	data, err := os.ReadFile(cm.originalLinkPath())
+	if os.IsNotExist(err) {
+		return links.Clone(), nil
+	}
+	if err != nil {
+		return links, fmt.Errorf("failed to read original link settings: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &links); err != nil {
+		return links, fmt.Errorf("failed to parse original link settings: %w", err)
+	}
+	return links.Clone(), nil
+}
+
+// SetOriginalLinks persists the original settings of links, removing the
+// record if there are none
+func (cm *ConfigManager) SetOriginalLinks(links OriginalLinks) error {
+	if len(links.MTUs) == 0 && len(links.MACs) == 0 {
+		if err := os.Remove(cm.originalLinkPath()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove original link settings: %w", err)
+		}
+		return nil
+	}
+	data, err := yaml.Marshal(links)
+	if err != nil {
+		return fmt.Errorf("failed to marshal original link settings: %w", err)
+	}
+	if err := os.WriteFile(cm.originalLinkPath(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write original link settings: %w", err)
+	}
+	return nil
+}
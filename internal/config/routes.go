@@ -0,0 +1,134 @@
+package config
+
+import "fmt"
+
+// DefaultDistance is the administrative distance of a static route target
+// without configured distance
+const DefaultDistance = 1
+
+// ProtocolsConfig represents routing protocol settings
+type ProtocolsConfig struct {
+	Static StaticConfig `yaml:"static,omitempty"`
+}
+
+// StaticConfig represents static routing
+type StaticConfig struct {
+	// Routes are the static routes by destination prefix
+	Routes map[string]StaticRoute `yaml:"route,omitempty"`
+}
+
+// StaticRoute represents the targets of a static route. Targets with the same
+// distance are used together (ECMP); the lowest distance is preferred.
+type StaticRoute struct {
+	// NextHops are the gateways by address
+	NextHops map[string]RouteTarget `yaml:"next_hop,omitempty"`
+	// Interfaces are the directly connected interfaces by name
+	Interfaces map[string]RouteTarget `yaml:"interface,omitempty"`
+	// Blackhole discards the traffic if set
+	Blackhole *RouteTarget `yaml:"blackhole,omitempty"`
+}
+
+// RouteTarget represents the settings of a static route target
+type RouteTarget struct {
+	// Distance is the administrative distance; zero means DefaultDistance
+	Distance int `yaml:"distance,omitempty"`
+}
+
+// EffectiveDistance returns the distance of the target, with DefaultDistance if unset
+func (t RouteTarget) EffectiveDistance() int {
+	if t.Distance == 0 {
+		return DefaultDistance
+	}
+	return t.Distance
+}
+
+// Empty reports whether the route has no targets
+func (r StaticRoute) Empty() bool {
+	return len(r.NextHops) == 0 && len(r.Interfaces) == 0 && r.Blackhole == nil
+}
+
+// clone returns a deep copy of the route
+func (r StaticRoute) clone() StaticRoute {
+	c := StaticRoute{
+		NextHops:   cloneTargets(r.NextHops),
+		Interfaces: cloneTargets(r.Interfaces),
+	}
+	if r.Blackhole != nil {
+		blackhole := *r.Blackhole
+		c.Blackhole = &blackhole
+	}
+	return c
+}
+
+// merge overlays the targets of other onto the route
+func (r *StaticRoute) merge(other StaticRoute) {
+	for gw, t := range other.NextHops {
+		if r.NextHops == nil {
+			r.NextHops = make(map[string]RouteTarget)
+		}
+		r.NextHops[gw] = t
+	}
+	for name, t := range other.Interfaces {
+		if r.Interfaces == nil {
+			r.Interfaces = make(map[string]RouteTarget)
+		}
+		r.Interfaces[name] = t
+	}
+	if other.Blackhole != nil {
+		blackhole := *other.Blackhole
+		r.Blackhole = &blackhole
+	}
+}
+
+// cloneTargets returns a copy of a target map, or nil for an empty one
+func cloneTargets(m map[string]RouteTarget) map[string]RouteTarget {
+	if len(m) == 0 {
+		return nil
+	}
+	c := make(map[string]RouteTarget, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// cloneRoutes returns a deep copy of a route map, or nil for an empty one
+func cloneRoutes(m map[string]StaticRoute) map[string]StaticRoute {
+	if len(m) == 0 {
+		return nil
+	}
+	c := make(map[string]StaticRoute, len(m))
+	for prefix, r := range m {
+		c[prefix] = r.clone()
+	}
+	return c
+}
+
+// StaticRoute returns the static route to a prefix and whether it is configured
+func (cm *ConfigManager) StaticRoute(prefix string) (StaticRoute, bool) {
+	r, ok := cm.Candidate.Protocols.Static.Routes[prefix]
+	return r.clone(), ok
+}
+
+// SetStaticRoute sets the static route to a prefix. A route without targets
+// is removed.
+func (cm *ConfigManager) SetStaticRoute(prefix string, route StaticRoute) {
+	static := &cm.Candidate.Protocols.Static
+	if route.Empty() {
+		delete(static.Routes, prefix)
+		return
+	}
+	if static.Routes == nil {
+		static.Routes = make(map[string]StaticRoute)
+	}
+	static.Routes[prefix] = route
+}
+
+// DeleteStaticRoute removes the static route to a prefix
+func (cm *ConfigManager) DeleteStaticRoute(prefix string) error {
+	if _, ok := cm.Candidate.Protocols.Static.Routes[prefix]; !ok {
+		return fmt.Errorf("static route %s is not configured", prefix)
+	}
+	delete(cm.Candidate.Protocols.Static.Routes, prefix)
+	return nil
+}
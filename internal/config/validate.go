@@ -0,0 +1,331 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"configure/internal/validator"
+)
+
+// Validate checks every setting of the configuration and the consistency
+// between settings. It returns all problems found, each prefixed with the
+// path of the setting.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(path string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+	}
+
+	if c.Hostname != "" {
+		check("hostname", validator.ValidateHostname(c.Hostname))
+	}
+	for _, name := range sortedKeys(c.Interfaces) {
+		iface := c.Interfaces[name]
+		for i, addr := range iface.Addresses {
+			switch {
+			case contains(iface.Addresses[:i], addr):
+				check("interfaces "+name+" address", fmt.Errorf("%s is listed twice", addr))
+			case addr != AddressDHCP && addr != AddressDHCPv6:
+				check("interfaces "+name+" address", validator.ValidateAddressWithPrefix(addr))
+			}
+		}
+		if iface.MAC != "" {
+			check("interfaces "+name+" mac", validator.ValidateMACAddress(iface.MAC))
+		}
+		if iface.Description != "" {
+			check("interfaces "+name+" description", validator.ValidateDescription(iface.Description))
+		}
+		if iface.MTU != 0 {
+			check("interfaces "+name+" mtu", validator.ValidateMTU(strconv.Itoa(iface.MTU)))
+		}
+		if iface.Speed != "" {
+			check("interfaces "+name+" speed", validator.ValidateSpeed(iface.Speed))
+		}
+		if iface.Duplex != "" {
+			check("interfaces "+name+" duplex", validator.ValidateDuplex(iface.Duplex))
+		}
+		for i, feature := range iface.Offload {
+			if contains(iface.Offload[:i], feature) {
+				check("interfaces "+name+" offload", fmt.Errorf("%s is listed twice", feature))
+			} else {
+				check("interfaces "+name+" offload", validator.ValidateOffload(feature))
+			}
+		}
+	}
+	for _, server := range c.DNS {
+		check("dns", validator.ValidateHostAddress(server))
+	}
+	if c.DefaultRoute != "" {
+		check("ip route default via", validator.ValidateGateway(c.DefaultRoute))
+	}
+	if n := c.System.CommitRevisions; n < 0 || n > MaxCommitRevisions {
+		check("system commit-revisions", fmt.Errorf("%d is out of range (expected 1-%d)", n, MaxCommitRevisions))
+	}
+
+	errs = append(errs, c.checkStaticRoutes()...)
+	errs = append(errs, c.checkInterfaces()...)
+	for _, name := range sortedKeys(c.Interfaces) {
+		errs = append(errs, checkLink(name, c.Interfaces[name])...)
+	}
+	errs = append(errs, c.checkDefaultRoute()...)
+	return errors.Join(errs...)
+}
+
+// ifaceAddress is a parsed interface address
+type ifaceAddress struct {
+	name   string
+	prefix netip.Prefix
+}
+
+// interfaceAddresses returns the parseable static interface addresses sorted
+// by interface name. Addresses without a prefix length are host addresses, as
+// they are when applied to the system.
+func (c *Config) interfaceAddresses() []ifaceAddress {
+	var res []ifaceAddress
+	for _, name := range sortedKeys(c.Interfaces) {
+		for _, addr := range c.Interfaces[name].Addresses {
+			if prefix, ok := parsePrefix(addr); ok {
+				res = append(res, ifaceAddress{name: name, prefix: prefix})
+			}
+		}
+	}
+	return res
+}
+
+// dynamicAddressing reports whether any interface obtains IPv4 (or IPv6)
+// addresses dynamically
+func (c *Config) dynamicAddressing(ipv6 bool) bool {
+	for _, iface := range c.Interfaces {
+		if ipv6 && (iface.HasAddress(AddressDHCPv6) || iface.IPv6.Autoconf) {
+			return true
+		}
+		if !ipv6 && iface.HasAddress(AddressDHCP) {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePrefix parses an address with an optional prefix length
+func parsePrefix(s string) (netip.Prefix, bool) {
+	if s == "" {
+		return netip.Prefix{}, false
+	}
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix, err == nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), true
+}
+
+// checkInterfaces reports host addresses that are the network or broadcast
+// address of their subnet, addresses and subnets shared between interfaces,
+// and MAC addresses that cannot be assigned to an interface
+func (c *Config) checkInterfaces() []error {
+	var errs []error
+	addrs := c.interfaceAddresses()
+	for i, a := range addrs {
+		path := "interfaces " + a.name + " address"
+		if reason := reservedHostAddress(a.prefix); reason != "" {
+			errs = append(errs, fmt.Errorf("%s: %s is the %s address of its subnet", path, a.prefix, reason))
+		}
+		for _, b := range addrs[:i] {
+			switch {
+			case a.name == b.name:
+				// Several addresses of one interface may share a subnet
+			case a.prefix.Addr() == b.prefix.Addr():
+				errs = append(errs, fmt.Errorf("%s: %s is also configured on %s", path, a.prefix.Addr(), b.name))
+			case a.prefix.Overlaps(b.prefix):
+				errs = append(errs, fmt.Errorf("%s: subnet %s overlaps %s on %s", path, a.prefix.Masked(), b.prefix.Masked(), b.name))
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(c.Interfaces) {
+		mac, err := net.ParseMAC(c.Interfaces[name].MAC)
+		if err != nil {
+			continue
+		}
+		path := "interfaces " + name + " mac"
+		switch {
+		case isZero(mac):
+			errs = append(errs, fmt.Errorf("%s: %s is the all-zero address", path, mac))
+		case mac[0]&1 == 1:
+			errs = append(errs, fmt.Errorf("%s: %s is a multicast address", path, mac))
+		}
+	}
+	return errs
+}
+
+// minIPv6MTU is the smallest MTU of a link that carries IPv6 (RFC 8200)
+const minIPv6MTU = 1280
+
+// checkLink reports link settings of an interface that cannot be applied
+// together: a fixed speed without fixed duplex or the reverse, half duplex
+// above 100 Mbit/s and an MTU too small for IPv6 on an interface using IPv6
+func checkLink(name string, iface InterfaceConfig) []error {
+	var errs []error
+	path := "interfaces " + name
+	speed, duplex := iface.LinkMode()
+	switch {
+	case speed != LinkAuto && duplex == LinkAuto:
+		errs = append(errs, fmt.Errorf("%s speed: speed %s requires duplex half or full", path, speed))
+	case speed == LinkAuto && duplex != LinkAuto:
+		errs = append(errs, fmt.Errorf("%s duplex: duplex %s requires a fixed speed", path, duplex))
+	case duplex == "half" && speed != "10" && speed != "100":
+		errs = append(errs, fmt.Errorf("%s duplex: half duplex is only supported at 10 or 100 Mbit/s", path))
+	}
+	if iface.MTU != 0 && iface.MTU < minIPv6MTU && usesIPv6(iface) {
+		errs = append(errs, fmt.Errorf("%s mtu: %d is below the IPv6 minimum of %d", path, iface.MTU, minIPv6MTU))
+	}
+	return errs
+}
+
+// usesIPv6 reports whether an interface has IPv6 addresses configured
+func usesIPv6(iface InterfaceConfig) bool {
+	if iface.HasAddress(AddressDHCPv6) || iface.IPv6.Autoconf {
+		return true
+	}
+	for _, addr := range iface.Addresses {
+		if prefix, ok := parsePrefix(addr); ok && prefix.Addr().Is6() {
+			return true
+		}
+	}
+	return false
+}
+
+// reservedHostAddress returns "network" or "broadcast" if the address of a
+// prefix cannot be used as a host address, or an empty string
+func reservedHostAddress(p netip.Prefix) string {
+	bits := p.Addr().BitLen()
+	// Point-to-point and host prefixes have no reserved addresses
+	if p.Bits() >= bits-1 {
+		return ""
+	}
+	if p.Addr() == p.Masked().Addr() {
+		return "network"
+	}
+	if p.Addr().Is4() && p.Addr() == lastAddr(p) {
+		return "broadcast"
+	}
+	return ""
+}
+
+// lastAddr returns the last address of a prefix
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Masked().Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// isZero reports whether all bytes of a MAC address are zero
+func isZero(mac net.HardwareAddr) bool {
+	for _, b := range mac {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDefaultRoute reports a default gateway that is not on the subnet of any
+// configured interface address. The check is skipped for link-local gateways,
+// which are always on-link, if addresses of the gateway's family are obtained
+// dynamically, and if no interface address of the gateway's family is
+// configured, since the interfaces are then managed outside this configuration.
+func (c *Config) checkDefaultRoute() []error {
+	gw, err := netip.ParseAddr(c.DefaultRoute)
+	if err != nil || gw.IsLinkLocalUnicast() || c.dynamicAddressing(gw.Is6()) {
+		return nil
+	}
+	var addrs []ifaceAddress
+	for _, a := range c.interfaceAddresses() {
+		if a.prefix.Addr().Is4() == gw.Is4() {
+			addrs = append(addrs, a)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil
+	}
+	for _, a := range addrs {
+		if a.prefix.Addr() == gw {
+			return []error{fmt.Errorf("ip route default via: %s is the address of %s itself", gw, a.name)}
+		}
+	}
+	for _, a := range addrs {
+		if a.prefix.Contains(gw) {
+			return nil
+		}
+	}
+	return []error{fmt.Errorf("ip route default via: %s is not reachable through any interface subnet", gw)}
+}
+
+// checkStaticRoutes reports invalid static routes: bad prefixes, next-hops
+// and distances, next-hops of the other address family, blackholes sharing a
+// distance with forwarding targets, and default routes that conflict with
+// ip route default
+func (c *Config) checkStaticRoutes() []error {
+	var errs []error
+	for _, prefix := range sortedKeys(c.Protocols.Static.Routes) {
+		route := c.Protocols.Static.Routes[prefix]
+		path := "protocols static route " + prefix
+		if err := validator.ValidatePrefix(prefix); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+		if route.Empty() {
+			errs = append(errs, fmt.Errorf("%s: no next-hop, interface or blackhole configured", path))
+		}
+		dst, dstErr := netip.ParsePrefix(prefix)
+		distances := make(map[int]bool)
+		for _, gw := range sortedKeys(route.NextHops) {
+			hopPath := path + " next-hop " + gw
+			if err := validator.ValidateGateway(gw); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", hopPath, err))
+			} else if addr, err := netip.ParseAddr(gw); err == nil && dstErr == nil && addr.Is4() != dst.Addr().Is4() {
+				errs = append(errs, fmt.Errorf("%s: next-hop and destination are of different address families", hopPath))
+			}
+			errs = append(errs, checkDistance(hopPath, route.NextHops[gw])...)
+			distances[route.NextHops[gw].EffectiveDistance()] = true
+		}
+		for _, name := range sortedKeys(route.Interfaces) {
+			errs = append(errs, checkDistance(path+" interface "+name, route.Interfaces[name])...)
+			distances[route.Interfaces[name].EffectiveDistance()] = true
+		}
+		if route.Blackhole != nil {
+			errs = append(errs, checkDistance(path+" blackhole", *route.Blackhole)...)
+			if d := route.Blackhole.EffectiveDistance(); distances[d] {
+				errs = append(errs, fmt.Errorf("%s blackhole: distance %d is also used by a next-hop or interface", path, d))
+			}
+		}
+		if dstErr == nil && dst.Bits() == 0 && c.DefaultRoute != "" {
+			if gw, err := netip.ParseAddr(c.DefaultRoute); err == nil && gw.Is4() == dst.Addr().Is4() {
+				errs = append(errs, fmt.Errorf("%s: conflicts with ip route default via %s", path, c.DefaultRoute))
+			}
+		}
+	}
+	return errs
+}
+
+// checkDistance reports a route target distance out of range
+func checkDistance(path string, t RouteTarget) []error {
+	if t.Distance == 0 {
+		return nil
+	}
+	if err := validator.ValidateDistance(strconv.Itoa(t.Distance)); err != nil {
+		return []error{fmt.Errorf("%s distance: %w", path, err)}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package lexer
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Error reports a malformed command line
+type Error struct {
+	// Column is the 1-based column, in characters, where the problem starts
+	Column int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at column %d", e.Msg, e.Column)
+}
+
+// state is the result of scanning a command line
+type state struct {
+	words []string
+	// current is the word being scanned when the line ended
+	current strings.Builder
+	// inWord is true if the line ended inside a word
+	inWord bool
+	// quote is the unterminated quote the line ended in, or 0
+	quote rune
+	// quoteColumn is the column of the unterminated quote
+	quoteColumn int
+	// escape is true if the line ended with an unescaped backslash
+	escape bool
+	// escapeColumn is the column of the trailing backslash
+	escapeColumn int
+	// comment is true if the line ended in a comment
+	comment bool
+}
+
+// scan splits a command line into words like a POSIX shell: words are
+// separated by whitespace, single quotes preserve everything literally,
+// double quotes allow \" and \\ escapes, a backslash outside quotes escapes
+// the next character and # at the start of a word starts a comment.
+func scan(line string) *state {
+	s := &state{}
+	for i, r := range []rune(line) {
+		column := i + 1
+		switch {
+		case s.escape:
+			// Inside double quotes only \" and \\ are escapes; the backslash
+			// is kept before any other character
+			if s.quote == '"' && r != '"' && r != '\\' {
+				s.current.WriteRune('\\')
+			}
+			s.current.WriteRune(r)
+			s.escape = false
+		case s.quote == '\'':
+			if r == '\'' {
+				s.quote = 0
+			} else {
+				s.current.WriteRune(r)
+			}
+		case s.quote == '"':
+			switch r {
+			case '"':
+				s.quote = 0
+			case '\\':
+				s.escape, s.escapeColumn = true, column
+			default:
+				s.current.WriteRune(r)
+			}
+		case unicode.IsSpace(r):
+			s.endWord()
+		case r == '#' && !s.inWord:
+			s.comment = true
+			return s
+		default:
+			s.inWord = true
+			switch r {
+			case '\'', '"':
+				s.quote, s.quoteColumn = r, column
+			case '\\':
+				s.escape, s.escapeColumn = true, column
+			default:
+				s.current.WriteRune(r)
+			}
+		}
+	}
+	return s
+}
+
+// endWord finishes the current word, if any
+func (s *state) endWord() {
+	if s.inWord {
+		s.words = append(s.words, s.current.String())
+		s.current.Reset()
+		s.inWord = false
+	}
+}
+
+// Split splits a command line into words. It returns an *Error for an
+// unterminated quote or a trailing backslash.
+func Split(line string) ([]string, error) {
+	s := scan(line)
+	switch {
+	case s.quote == '\'':
+		return nil, &Error{Column: s.quoteColumn, Msg: "unterminated single quote"}
+	case s.quote == '"':
+		return nil, &Error{Column: s.quoteColumn, Msg: "unterminated double quote"}
+	case s.escape:
+		return nil, &Error{Column: s.escapeColumn, Msg: "trailing backslash"}
+	}
+	s.endWord()
+	return s.words, nil
+}
+
+// SplitPartial splits a command line that is still being typed. It returns
+// the complete words and the word being typed, which is empty if the line
+// ends with whitespace. Unterminated quotes are allowed. ok is false if the
+// line ends in a comment.
+func SplitPartial(line string) (words []string, current string, ok bool) {
+	s := scan(line)
+	if s.comment {
+		return nil, "", false
+	}
+	return s.words, s.current.String(), true
+}
+
+// Quote returns word quoted so that Split reads it back as a single word.
+// Words without special characters are returned unchanged.
+func Quote(word string) string {
+	if word != "" && !strings.ContainsFunc(word, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`'"\#`, r)
+	}) {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
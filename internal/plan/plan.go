@@ -0,0 +1,537 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"configure/internal/config"
+	"configure/internal/system"
+)
+
+// ResolverServices are restarted after resolv.conf is written
+var ResolverServices = []string{"resolvconf.service", "systemd-resolved.service"}
+
+// Step is a single system operation performed by a commit
+type Step struct {
+	// Description is the operation as shown by commit dry-run
+	Description string
+	// Apply performs the operation
+	Apply func(b system.Backend) error
+	// Undo reverts the operation after a later step failed.
+	// It is nil for operations that leave nothing to revert.
+	Undo func(b system.Backend) error
+}
+
+// Error reports the step that failed during Execute and the outcome of
+// undoing the steps applied before it
+type Error struct {
+	// Step is the description of the failed step
+	Step string
+	Err  error
+	// Undone is the number of steps that were applied and then undone
+	Undone int
+	// UndoErrors are the failures that occurred while undoing
+	UndoErrors []error
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s failed: %v", e.Step, e.Err)
+	var opErr *system.OpError
+	if errors.As(e.Err, &opErr) {
+		// The operation error already names the operation
+		msg = fmt.Sprintf("%s failed: %v", opErr.Op, opErr.Err)
+	}
+	switch {
+	case len(e.UndoErrors) > 0:
+		return fmt.Sprintf("%s; undo of applied operations failed: %v", msg, errors.Join(e.UndoErrors...))
+	case e.Undone > 0:
+		return fmt.Sprintf("%s; %d applied operations were undone", msg, e.Undone)
+	default:
+		return msg
+	}
+}
+
+// Unwrap returns the error of the failed step
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Plan is the ordered list of system operations that applies a configuration
+type Plan struct {
+	Steps []Step
+	// Original are the MTUs and MAC addresses links had before they were first
+	// configured. Executing the plan records the links that get a configured
+	// MTU or MAC and forgets those whose original setting is restored; the
+	// caller persists the result for later plans.
+	Original config.OriginalLinks
+}
+
+// New returns the plan that replaces the old configuration on the system with
+// the new one. Only subsystems whose configuration differs are touched, so
+// applying a configuration over itself yields an empty plan. original are the
+// link settings recorded by earlier plans; they are not modified.
+func New(old, new *config.Config, original config.OriginalLinks) *Plan {
+	p := &Plan{Original: original.Clone()}
+	p.addInterfaces(old, new)
+	p.addDNS(old, new)
+	p.addDefaultRoute(old, new)
+	p.addStaticRoutes(old, new)
+	return p
+}
+
+// Execute performs the steps in order. If a step fails, the steps applied
+// before it are undone in reverse order and an *Error is returned.
+func (p *Plan) Execute(b system.Backend) error {
+	for i, s := range p.Steps {
+		if err := s.Apply(b); err != nil {
+			e := &Error{Step: firstLine(s.Description), Err: err}
+			e.Undone, e.UndoErrors = p.undo(b, i)
+			return e
+		}
+	}
+	return nil
+}
+
+// Undo reverts all steps of a plan that was executed successfully, in
+// reverse order
+func (p *Plan) Undo(b system.Backend) error {
+	_, errs := p.undo(b, len(p.Steps))
+	return errors.Join(errs...)
+}
+
+// undo reverts the first n steps in reverse order and returns the number of
+// steps undone and the failures
+func (p *Plan) undo(b system.Backend, n int) (int, []error) {
+	undone := 0
+	var errs []error
+	for j := n - 1; j >= 0; j-- {
+		undo := p.Steps[j].Undo
+		if undo == nil {
+			continue
+		}
+		if err := undo(b); err != nil {
+			errs = append(errs, fmt.Errorf("undo %s: %w", firstLine(p.Steps[j].Description), err))
+		} else {
+			undone++
+		}
+	}
+	return undone, errs
+}
+
+// String renders the steps, one operation per line
+func (p *Plan) String() string {
+	var sb strings.Builder
+	for _, s := range p.Steps {
+		sb.WriteString(s.Description)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// add appends a step to the plan
+func (p *Plan) add(description string, apply, undo func(b system.Backend) error) {
+	p.Steps = append(p.Steps, Step{Description: description, Apply: apply, Undo: undo})
+}
+
+// addInterfaces adds the steps that remove addresses and DHCP clients no
+// longer configured, set changed MAC addresses and link settings, add new
+// addresses and DHCP clients, switch IPv6 autoconfiguration and set the
+// administrative state
+func (p *Plan) addInterfaces(old, new *config.Config) {
+	for _, name := range sortedInterfaces(old, new) {
+		name := name
+		oldIface := old.Interfaces[name]
+		iface, ok := new.Interfaces[name]
+		for _, addr := range oldIface.Addresses {
+			if !ok || !iface.HasAddress(addr) {
+				p.addAddress(name, addr, false)
+			}
+		}
+		if iface.MAC != oldIface.MAC {
+			p.addMAC(name, iface.MAC)
+		}
+		p.addLink(name, oldIface, iface)
+		for _, addr := range iface.Addresses {
+			if !oldIface.HasAddress(addr) {
+				p.addAddress(name, addr, true)
+			}
+		}
+		// A removed interface has autoconf disabled like any other setting
+		if enabled := iface.IPv6.Autoconf; enabled != oldIface.IPv6.Autoconf {
+			p.add(fmt.Sprintf("ipv6 autoconf %s %s", system.OnOff(enabled), name), func(b system.Backend) error {
+				return b.SetIPv6Autoconf(name, enabled)
+			}, func(b system.Backend) error {
+				return b.SetIPv6Autoconf(name, !enabled)
+			})
+		}
+		// The link goes up or down once everything else is configured
+		if up := !iface.Disable; up == oldIface.Disable {
+			p.add(fmt.Sprintf("link set %s %s", name, system.UpDown(up)), func(b system.Backend) error {
+				return b.SetLinkUp(name, up)
+			}, func(b system.Backend) error {
+				return b.SetLinkUp(name, !up)
+			})
+		}
+	}
+}
+
+// addLink adds the steps that change the MTU, description, speed and duplex
+// and offload features of an interface. Settings that are no longer
+// configured return to their defaults.
+func (p *Plan) addLink(name string, oldIface, iface config.InterfaceConfig) {
+	if iface.MTU != oldIface.MTU {
+		p.addMTU(name, iface.MTU)
+	}
+	if alias, oldAlias := iface.Description, oldIface.Description; alias != oldAlias {
+		p.add(strings.TrimSpace(fmt.Sprintf("link set %s alias %s", name, alias)), func(b system.Backend) error {
+			return b.SetLinkAlias(name, alias)
+		}, func(b system.Backend) error {
+			return b.SetLinkAlias(name, oldAlias)
+		})
+	}
+	speed, duplex := iface.LinkMode()
+	oldSpeed, oldDuplex := oldIface.LinkMode()
+	if speed != oldSpeed || duplex != oldDuplex {
+		p.add(linkModeDescription(name, speed, duplex), func(b system.Backend) error {
+			return b.SetLinkMode(name, speed, duplex)
+		}, func(b system.Backend) error {
+			return b.SetLinkMode(name, oldSpeed, oldDuplex)
+		})
+	}
+	for _, feature := range oldIface.Offload {
+		if !iface.HasOffload(feature) {
+			p.addOffload(name, feature, false)
+		}
+	}
+	for _, feature := range iface.Offload {
+		if !oldIface.HasOffload(feature) {
+			p.addOffload(name, feature, true)
+		}
+	}
+}
+
+// addOffload adds the step that switches an offload feature of an interface
+func (p *Plan) addOffload(name, feature string, enabled bool) {
+	p.add(fmt.Sprintf("ethtool %s offload %s %s", name, feature, system.OnOff(enabled)), func(b system.Backend) error {
+		return b.SetLinkOffload(name, feature, enabled)
+	}, func(b system.Backend) error {
+		return b.SetLinkOffload(name, feature, !enabled)
+	})
+}
+
+// linkModeDescription describes the step that sets the speed and duplex of a link
+func linkModeDescription(name, speed, duplex string) string {
+	if speed == config.LinkAuto {
+		return fmt.Sprintf("ethtool %s autoneg on", name)
+	}
+	return fmt.Sprintf("ethtool %s speed %s duplex %s autoneg off", name, speed, duplex)
+}
+
+// addMAC adds the step that sets the MAC address of a link. An empty MAC
+// restores the address the link had before one was configured, and adds no
+// step if that is not known. The undo restores the address the link had right
+// before the step.
+func (p *Plan) addMAC(name, mac string) {
+	original, known := p.Original.MACs[name]
+	target := mac
+	if target == "" {
+		if !known {
+			return
+		}
+		target = original
+	}
+
+	var previous string
+	p.add(fmt.Sprintf("link set %s address %s", name, target), func(b system.Backend) error {
+		current, err := b.LinkMAC(name)
+		if err != nil {
+			return err
+		}
+		if err := b.SetLinkMAC(name, target); err != nil {
+			return err
+		}
+		previous = current
+		if mac == "" {
+			delete(p.Original.MACs, name)
+		} else if !known && current != "" {
+			p.Original.MACs[name] = current
+		}
+		return nil
+	}, func(b system.Backend) error {
+		// Links such as tunnels have no hardware address
+		if previous != "" {
+			if err := b.SetLinkMAC(name, previous); err != nil {
+				return err
+			}
+		}
+		if known {
+			p.Original.MACs[name] = original
+		} else {
+			delete(p.Original.MACs, name)
+		}
+		return nil
+	})
+}
+
+// addMTU adds the step that sets the MTU of a link. An MTU of zero restores
+// the MTU the link had before one was configured, or DefaultMTU if that is
+// not known. The undo restores the MTU the link had right before the step.
+func (p *Plan) addMTU(name string, mtu int) {
+	original, known := p.Original.MTUs[name]
+	target := mtu
+	if target == 0 {
+		target = config.DefaultMTU
+		if known {
+			target = original
+		}
+	}
+
+	var previous int
+	p.add(fmt.Sprintf("link set %s mtu %d", name, target), func(b system.Backend) error {
+		current, err := b.LinkMTU(name)
+		if err != nil {
+			return err
+		}
+		if err := b.SetLinkMTU(name, target); err != nil {
+			return err
+		}
+		previous = current
+		if mtu == 0 {
+			delete(p.Original.MTUs, name)
+		} else if !known {
+			p.Original.MTUs[name] = current
+		}
+		return nil
+	}, func(b system.Backend) error {
+		if err := b.SetLinkMTU(name, previous); err != nil {
+			return err
+		}
+		if known {
+			p.Original.MTUs[name] = original
+		} else {
+			delete(p.Original.MTUs, name)
+		}
+		return nil
+	})
+}
+
+// addAddress adds the step that adds or removes an interface address entry,
+// which is a static address or a DHCP or DHCPv6 client
+func (p *Plan) addAddress(name, addr string, add bool) {
+	var start, stop func(b system.Backend) error
+	var startDesc, stopDesc string
+	switch addr {
+	case config.AddressDHCP, config.AddressDHCPv6:
+		ipv6 := addr == config.AddressDHCPv6
+		start = func(b system.Backend) error { return b.StartDHCP(name, ipv6) }
+		stop = func(b system.Backend) error { return b.StopDHCP(name, ipv6) }
+		startDesc, stopDesc = addr+" start "+name, addr+" stop "+name
+	default:
+		start = func(b system.Backend) error { return b.AddAddress(name, addr) }
+		stop = func(b system.Backend) error { return b.DeleteAddress(name, addr) }
+		startDesc = fmt.Sprintf("address add %s dev %s", addr, name)
+		stopDesc = fmt.Sprintf("address del %s dev %s", addr, name)
+	}
+	if add {
+		p.add(startDesc, start, stop)
+	} else {
+		p.add(stopDesc, stop, start)
+	}
+}
+
+// addDNS adds the steps that write resolv.conf and restart the resolver
+// services if the DNS servers or their order changed
+func (p *Plan) addDNS(old, new *config.Config) {
+	if equalStrings(old.DNS, new.DNS) {
+		return
+	}
+	servers := append([]string(nil), new.DNS...)
+	oldServers := append([]string(nil), old.DNS...)
+	description := "write resolv.conf"
+	if content := system.ResolvConf(servers); content != "" {
+		description += "\n    " + strings.ReplaceAll(content, "\n", "\n    ")
+	}
+	// The restarts themselves leave nothing to revert, so undoing the write
+	// restarts the services again to pick up the old servers
+	p.add(description, func(b system.Backend) error {
+		return b.WriteResolvConf(servers)
+	}, func(b system.Backend) error {
+		if err := b.WriteResolvConf(oldServers); err != nil {
+			return err
+		}
+		for _, service := range ResolverServices {
+			if err := b.RestartService(service); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	for _, service := range ResolverServices {
+		service := service
+		p.add("restart "+service, func(b system.Backend) error {
+			return b.RestartService(service)
+		}, nil)
+	}
+}
+
+// addDefaultRoute adds the step that sets or removes the default route if it changed
+func (p *Plan) addDefaultRoute(old, new *config.Config) {
+	gateway, oldGateway := new.DefaultRoute, old.DefaultRoute
+	restore := func(b system.Backend) error {
+		if oldGateway == "" {
+			return b.DeleteDefaultRoute(gateway)
+		}
+		return b.ReplaceDefaultRoute(oldGateway)
+	}
+	switch {
+	case gateway == oldGateway:
+	case gateway != "":
+		p.add("route replace default via "+gateway, func(b system.Backend) error {
+			return b.ReplaceDefaultRoute(gateway)
+		}, restore)
+	default:
+		p.add("route del default via "+oldGateway, func(b system.Backend) error {
+			return b.DeleteDefaultRoute(oldGateway)
+		}, restore)
+	}
+}
+
+// StaticRoutes returns the kernel routes of the static routes of a
+// configuration, ordered by prefix and metric. The targets of a prefix with the
+// same distance form one route, with several next-hops for ECMP.
+func StaticRoutes(cfg *config.Config) []system.Route {
+	var routes []system.Route
+	for _, prefix := range sortedKeys(cfg.Protocols.Static.Routes) {
+		r := cfg.Protocols.Static.Routes[prefix]
+		byMetric := make(map[int]*system.Route)
+		route := func(distance int) *system.Route {
+			if byMetric[distance] == nil {
+				byMetric[distance] = &system.Route{Prefix: prefix, Metric: distance}
+			}
+			return byMetric[distance]
+		}
+		for _, gw := range sortedKeys(r.NextHops) {
+			rt := route(r.NextHops[gw].EffectiveDistance())
+			rt.NextHops = append(rt.NextHops, system.NextHop{Gateway: gw})
+		}
+		for _, name := range sortedKeys(r.Interfaces) {
+			rt := route(r.Interfaces[name].EffectiveDistance())
+			rt.NextHops = append(rt.NextHops, system.NextHop{Link: name})
+		}
+		if r.Blackhole != nil {
+			route(r.Blackhole.EffectiveDistance()).Blackhole = true
+		}
+		metrics := make([]int, 0, len(byMetric))
+		for metric := range byMetric {
+			metrics = append(metrics, metric)
+		}
+		sort.Ints(metrics)
+		for _, metric := range metrics {
+			rt := byMetric[metric]
+			if rt.Blackhole {
+				// Validation keeps blackholes apart from forwarding targets
+				rt.NextHops = nil
+			}
+			routes = append(routes, *rt)
+		}
+	}
+	return routes
+}
+
+// addStaticRoutes adds the steps that remove static routes that are no longer
+// configured and add or replace those that are new or changed
+func (p *Plan) addStaticRoutes(old, new *config.Config) {
+	oldRoutes, newRoutes := StaticRoutes(old), StaticRoutes(new)
+	oldByKey, newByKey := routesByKey(oldRoutes), routesByKey(newRoutes)
+	for _, r := range oldRoutes {
+		r := r
+		if _, ok := newByKey[routeKey(r)]; ok {
+			continue
+		}
+		p.add("route del "+r.String(), func(b system.Backend) error {
+			return b.DeleteRoute(r)
+		}, func(b system.Backend) error {
+			return b.ReplaceRoute(r)
+		})
+	}
+	for _, r := range newRoutes {
+		r := r
+		oldRoute, ok := oldByKey[routeKey(r)]
+		if ok && oldRoute.String() == r.String() {
+			continue
+		}
+		undo := func(b system.Backend) error {
+			return b.DeleteRoute(r)
+		}
+		if ok {
+			undo = func(b system.Backend) error {
+				return b.ReplaceRoute(oldRoute)
+			}
+		}
+		p.add("route replace "+r.String(), func(b system.Backend) error {
+			return b.ReplaceRoute(r)
+		}, undo)
+	}
+}
+
+// routeKey identifies a kernel route: routes with the same prefix and metric replace each other
+func routeKey(r system.Route) string {
+	return fmt.Sprintf("%s metric %d", r.Prefix, r.Metric)
+}
+
+// routesByKey indexes routes by routeKey
+func routesByKey(routes []system.Route) map[string]system.Route {
+	m := make(map[string]system.Route, len(routes))
+	for _, r := range routes {
+		m[routeKey(r)] = r
+	}
+	return m
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedInterfaces returns the names of the interfaces in either configuration, sorted
+func sortedInterfaces(old, new *config.Config) []string {
+	var names []string
+	for name := range new.Interfaces {
+		names = append(names, name)
+	}
+	for name := range old.Interfaces {
+		if _, ok := new.Interfaces[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// equalStrings reports whether two string slices have the same elements in the same order
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// firstLine returns the first line of a step description
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
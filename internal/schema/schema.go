@@ -0,0 +1,204 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Handler runs a command. It receives the values matched by the value nodes
+// along the command path, in order.
+type Handler func(args []string) error
+
+// Format is a value format shown by ? help, e.g. "<x.x.x.x/x>" with
+// "IPv4 address and prefix"
+type Format struct {
+	Hint string
+	Help string
+}
+
+// ValueType describes the values accepted by a value node
+type ValueType struct {
+	// Name is the placeholder shown in usages and errors, e.g. "<address>"
+	Name string
+	// Formats lists the accepted formats for ? help; if empty, the node's
+	// name and help are shown instead
+	Formats []Format
+	// Validate checks a value; nil accepts anything
+	Validate func(string) error
+	// Complete returns the candidates offered by tab completion; nil offers none
+	Complete func() []string
+}
+
+// Node is a node of the command schema. A node either matches a literal
+// keyword or, if Value is set, any value accepted by the value type.
+type Node struct {
+	Keyword string
+	Value   *ValueType
+	// Rest makes a value node consume the remaining words as a single value
+	Rest     bool
+	Help     string
+	Children []*Node
+	// Run executes the command ending at this node; nil if the command is incomplete
+	Run Handler
+}
+
+// Name returns the keyword of a keyword node or the placeholder of a value node
+func (n *Node) Name() string {
+	if n.Value != nil {
+		return n.Value.Name
+	}
+	return n.Keyword
+}
+
+// Child returns the child matching a word. Keywords take precedence over values.
+func (n *Node) Child(word string) *Node {
+	for _, c := range n.Children {
+		if c.Value == nil && c.Keyword == word {
+			return c
+		}
+	}
+	for _, c := range n.Children {
+		if c.Value != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// Parse resolves the words of a command line against the schema and returns
+// the node the command ends at and the values matched along the way.
+// Values are validated by their value types.
+func Parse(root *Node, words []string) (*Node, []string, error) {
+	node := root
+	var args []string
+	for i, word := range words {
+		child := node.Child(word)
+		if child == nil {
+			return nil, nil, fmt.Errorf("unknown command: %s", strings.Join(words, " "))
+		}
+		if child.Value != nil {
+			if child.Rest {
+				word = strings.Join(words[i:], " ")
+			}
+			if child.Value.Validate != nil {
+				if err := child.Value.Validate(word); err != nil {
+					return nil, nil, fmt.Errorf("invalid %s: %w", strings.Trim(child.Value.Name, "<>"), err)
+				}
+			}
+			args = append(args, word)
+		}
+		node = child
+		if child.Rest {
+			break
+		}
+	}
+	return node, args, nil
+}
+
+// Execute parses a command line and runs its handler
+func Execute(root *Node, words []string) error {
+	node, args, err := Parse(root, words)
+	if err != nil {
+		return err
+	}
+	if node.Run == nil {
+		return fmt.Errorf("incomplete command: %s", strings.Join(words, " "))
+	}
+	return node.Run(args)
+}
+
+// Walk resolves the words against the schema like Parse, but without
+// validating values. It returns nil if a word matches no child.
+func Walk(root *Node, words []string) *Node {
+	node := root
+	for _, word := range words {
+		if node.Rest {
+			return node
+		}
+		node = node.Child(word)
+		if node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// Candidate is a completion candidate with the help text of its node
+type Candidate struct {
+	Word string
+	Help string
+}
+
+// Candidates returns the keywords and value candidates of the children of a
+// node that start with prefix, sorted by word
+func Candidates(node *Node, prefix string) []Candidate {
+	var res []Candidate
+	seen := make(map[string]bool)
+	for _, c := range node.Children {
+		var words []string
+		if c.Value == nil {
+			words = []string{c.Keyword}
+		} else if c.Value.Complete != nil {
+			words = c.Value.Complete()
+		}
+		for _, word := range words {
+			if strings.HasPrefix(word, prefix) && !seen[word] {
+				seen[word] = true
+				res = append(res, Candidate{Word: word, Help: c.Help})
+			}
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Word < res[j].Word })
+	return res
+}
+
+// Completions returns the words of the completion candidates of a node
+func Completions(node *Node, prefix string) []string {
+	var res []string
+	for _, c := range Candidates(node, prefix) {
+		res = append(res, c.Word)
+	}
+	return res
+}
+
+// Help returns one line per child of a node with its name and help text.
+// Value nodes show one line per accepted format.
+func Help(node *Node) []string {
+	var lines []string
+	for _, c := range node.Children {
+		if c.Value != nil && len(c.Value.Formats) > 0 {
+			for _, f := range c.Value.Formats {
+				lines = append(lines, fmt.Sprintf("%-20s %s", f.Hint, f.Help))
+			}
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%-20s %s", c.Name(), c.Help))
+	}
+	if node.Run != nil {
+		lines = append(lines, fmt.Sprintf("%-20s %s", "<Enter>", "Execute the current command"))
+	}
+	return lines
+}
+
+// Usage is a complete command of the schema with its help text
+type Usage struct {
+	Command string
+	Help    string
+}
+
+// Usages returns every complete command below a node in schema order
+func Usages(node *Node) []Usage {
+	var res []Usage
+	var walk func(n *Node, path []string)
+	walk = func(n *Node, path []string) {
+		if n.Run != nil && len(path) > 0 {
+			res = append(res, Usage{Command: strings.Join(path, " "), Help: n.Help})
+		}
+		for _, c := range n.Children {
+			walk(c, append(path[:len(path):len(path)], c.Name()))
+		}
+	}
+	walk(node, nil)
+	return res
+}
@@ -0,0 +1,144 @@
+package system
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Backend applies configuration to the operating system.
+// HandleCommit performs every system change through a Backend, so tests can
+// record the operations instead and other platforms can provide their own.
+type Backend interface {
+	// WriteResolvConf writes the DNS servers to the resolver configuration
+	WriteResolvConf(servers []string) error
+	// RestartService restarts a system service
+	RestartService(name string) error
+	// ReplaceDefaultRoute sets the default route via the given gateway,
+	// replacing an existing default route
+	ReplaceDefaultRoute(gateway string) error
+	// DeleteDefaultRoute removes the main table default route via the
+	// given gateway, leaving other default routes alone
+	DeleteDefaultRoute(gateway string) error
+	// ReplaceRoute adds a route, replacing an existing one with the same
+	// prefix and metric
+	ReplaceRoute(r Route) error
+	// DeleteRoute removes a route. Deleting a route that is not present is
+	// not an error.
+	DeleteRoute(r Route) error
+	// LinkMAC returns the current hardware address of a link
+	LinkMAC(link string) (string, error)
+	// SetLinkMAC sets the hardware address of a link
+	SetLinkMAC(link, mac string) error
+	// SetLinkAlias sets the description of a link; an empty alias clears it
+	SetLinkAlias(link, alias string) error
+	// LinkMTU returns the current MTU of a link
+	LinkMTU(link string) (int, error)
+	// SetLinkMTU sets the MTU of a link
+	SetLinkMTU(link string, mtu int) error
+	// SetLinkUp sets a link administratively up or down
+	SetLinkUp(link string, up bool) error
+	// SetLinkMode sets the ethernet speed and duplex of a link, or enables
+	// autonegotiation if speed is "auto"
+	SetLinkMode(link, speed, duplex string) error
+	// SetLinkOffload enables or disables an ethtool offload feature of a link
+	SetLinkOffload(link, feature string, enabled bool) error
+	// AddAddress adds an address in CIDR notation to a link.
+	// Adding an address that is already present is not an error.
+	AddAddress(link, address string) error
+	// DeleteAddress removes an address in CIDR notation from a link
+	DeleteAddress(link, address string) error
+	// StartDHCP starts a DHCP client, or a DHCPv6 client if ipv6 is set, on a link
+	StartDHCP(link string, ipv6 bool) error
+	// StopDHCP stops the DHCP or DHCPv6 client of a link, releasing its lease
+	StopDHCP(link string, ipv6 bool) error
+	// SetIPv6Autoconf enables or disables IPv6 stateless address autoconfiguration on a link
+	SetIPv6Autoconf(link string, enabled bool) error
+	// Links returns the names of the network interfaces present on the system
+	Links() ([]string, error)
+}
+
+// DefaultResolvConfPath is the resolver configuration file written on commit
+const DefaultResolvConfPath = "/etc/resolv.conf"
+
+// OpError reports the system operation that failed
+type OpError struct {
+	Op  string
+	Err error
+}
+
+// Error implements the error interface
+func (e *OpError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Op, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// opError wraps err with the operation description, or returns nil if err is nil
+func opError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &OpError{Op: op, Err: err}
+}
+
+// interfaceNames returns the names of the network interfaces known to the Go runtime
+func interfaceNames() ([]string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, opError("list interfaces", err)
+	}
+	names := make([]string, 0, len(ifaces))
+	for _, iface := range ifaces {
+		names = append(names, iface.Name)
+	}
+	return names, nil
+}
+
+// ResolvConf renders the resolver configuration for the DNS servers
+func ResolvConf(servers []string) string {
+	content := ""
+	for i, server := range servers {
+		if i > 0 {
+			content += "\n"
+		}
+		content += "nameserver " + server
+	}
+	return content
+}
+
+// splitZone splits the zone from an IPv6 link-local address such as
+// fe80::1%eth0, which names the interface the address is reachable on
+func splitZone(addr string) (string, string) {
+	if i := strings.LastIndex(addr, "%"); i >= 0 {
+		return addr[:i], addr[i+1:]
+	}
+	return addr, ""
+}
+
+// dhcpProtocol returns the name of the DHCP protocol used in operation names
+func dhcpProtocol(ipv6 bool) string {
+	if ipv6 {
+		return "dhcpv6"
+	}
+	return "dhcp"
+}
+
+// UpDown returns the administrative state of a link as used in operation names
+func UpDown(up bool) string {
+	if up {
+		return "up"
+	}
+	return "down"
+}
+
+// OnOff returns the state of a switch as used in operation names
+func OnOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,6 @@
+package system
+
+// NewBackend returns the Backend for the current platform
+func NewBackend() Backend {
+	return NewNetlink()
+}
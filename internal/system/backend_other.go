@@ -0,0 +1,49 @@
+//go:build !linux
+
+package system
+
+import (
+	"errors"
+	"runtime"
+)
+
+// errUnsupported is returned by every operation on platforms without a backend
+var errUnsupported = errors.New("applying configuration is not supported on " + runtime.GOOS)
+
+// unsupported is the Backend for platforms that cannot apply configuration
+type unsupported struct{}
+
+// NewBackend returns the Backend for the current platform
+func NewBackend() Backend {
+	return unsupported{}
+}
+
+func (unsupported) WriteResolvConf(servers []string) error   { return errUnsupported }
+func (unsupported) RestartService(name string) error         { return errUnsupported }
+func (unsupported) ReplaceDefaultRoute(gateway string) error { return errUnsupported }
+func (unsupported) DeleteDefaultRoute(gateway string) error  { return errUnsupported }
+func (unsupported) ReplaceRoute(r Route) error               { return errUnsupported }
+func (unsupported) DeleteRoute(r Route) error                { return errUnsupported }
+func (unsupported) LinkMAC(link string) (string, error)      { return "", errUnsupported }
+func (unsupported) SetLinkMAC(link, mac string) error        { return errUnsupported }
+func (unsupported) AddAddress(link, address string) error    { return errUnsupported }
+func (unsupported) DeleteAddress(link, address string) error { return errUnsupported }
+func (unsupported) SetLinkAlias(link, alias string) error    { return errUnsupported }
+func (unsupported) LinkMTU(link string) (int, error)         { return 0, errUnsupported }
+func (unsupported) SetLinkMTU(link string, mtu int) error    { return errUnsupported }
+func (unsupported) SetLinkUp(link string, up bool) error     { return errUnsupported }
+func (unsupported) SetLinkMode(link, speed, duplex string) error {
+	return errUnsupported
+}
+func (unsupported) SetLinkOffload(link, feature string, enabled bool) error {
+	return errUnsupported
+}
+func (unsupported) StartDHCP(link string, ipv6 bool) error { return errUnsupported }
+func (unsupported) StopDHCP(link string, ipv6 bool) error  { return errUnsupported }
+func (unsupported) SetIPv6Autoconf(link string, enabled bool) error {
+	return errUnsupported
+}
+
+// Links returns the network interfaces known to the Go runtime, which does
+// not need a platform backend
+func (unsupported) Links() ([]string, error) { return interfaceNames() }
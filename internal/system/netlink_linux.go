@@ -0,0 +1,277 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// Netlink is a Backend that configures links, addresses and routes through
+// the kernel netlink interface. Resolver configuration and services are
+// still handled by the embedded Shell backend.
+type Netlink struct {
+	*Shell
+}
+
+// NewNetlink creates a Netlink backend
+func NewNetlink() *Netlink {
+	return &Netlink{Shell: NewShell()}
+}
+
+// ReplaceDefaultRoute sets the default route via the given gateway,
+// replacing an existing default route of the same address family
+func (n *Netlink) ReplaceDefaultRoute(gateway string) error {
+	op := "route replace default via " + gateway
+	route, err := defaultRoute(gateway)
+	if err != nil {
+		return opError(op, err)
+	}
+	return opError(op, netlink.RouteReplace(route))
+}
+
+// DeleteDefaultRoute removes the default route via the given gateway from the
+// main table, doing nothing if it is not present. Default routes of the other
+// address family or through other gateways are left alone.
+func (n *Netlink) DeleteDefaultRoute(gateway string) error {
+	op := "route del default via " + gateway
+	route, err := defaultRoute(gateway)
+	if err != nil {
+		return opError(op, err)
+	}
+	if err := netlink.RouteDel(route); err != nil && !errors.Is(err, unix.ESRCH) {
+		return opError(op, err)
+	}
+	return nil
+}
+
+// defaultRoute returns the main table default route via a gateway, which may
+// carry an interface zone
+func defaultRoute(gateway string) (*netlink.Route, error) {
+	addr, zone := splitZone(gateway)
+	gw := net.ParseIP(addr)
+	if gw == nil {
+		return nil, fmt.Errorf("invalid gateway address")
+	}
+	route := &netlink.Route{
+		Dst:   defaultDst(gw),
+		Gw:    gw,
+		Table: unix.RT_TABLE_MAIN,
+	}
+	if zone != "" {
+		link, err := netlink.LinkByName(zone)
+		if err != nil {
+			return nil, err
+		}
+		route.LinkIndex = link.Attrs().Index
+	}
+	return route, nil
+}
+
+// ReplaceRoute adds a route, replacing an existing one with the same prefix and metric
+func (n *Netlink) ReplaceRoute(r Route) error {
+	op := "route replace " + r.String()
+	route, err := netlinkRoute(r)
+	if err != nil {
+		return opError(op, err)
+	}
+	return opError(op, netlink.RouteReplace(route))
+}
+
+// DeleteRoute removes a route, doing nothing if it is not present
+func (n *Netlink) DeleteRoute(r Route) error {
+	op := "route del " + r.String()
+	route, err := netlinkRoute(r)
+	if err != nil {
+		return opError(op, err)
+	}
+	if err := netlink.RouteDel(route); err != nil && !errors.Is(err, unix.ESRCH) {
+		return opError(op, err)
+	}
+	return nil
+}
+
+// netlinkRoute converts a Route, resolving the interfaces of its next-hops
+func netlinkRoute(r Route) (*netlink.Route, error) {
+	_, dst, err := net.ParseCIDR(r.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	route := &netlink.Route{Dst: dst, Priority: r.Metric}
+	if r.Blackhole {
+		route.Type = unix.RTN_BLACKHOLE
+		return route, nil
+	}
+	var hops []*netlink.NexthopInfo
+	for _, nh := range r.NextHops {
+		gw, zone := splitZone(nh.Gateway)
+		hop := &netlink.NexthopInfo{}
+		if gw != "" {
+			if hop.Gw = net.ParseIP(gw); hop.Gw == nil {
+				return nil, fmt.Errorf("invalid gateway address %s", gw)
+			}
+		}
+		if name := nh.link(zone); name != "" {
+			link, err := netlink.LinkByName(name)
+			if err != nil {
+				return nil, err
+			}
+			hop.LinkIndex = link.Attrs().Index
+		}
+		hops = append(hops, hop)
+	}
+	if len(hops) == 1 {
+		route.Gw, route.LinkIndex = hops[0].Gw, hops[0].LinkIndex
+	} else {
+		route.MultiPath = hops
+	}
+	return route, nil
+}
+
+// LinkMAC returns the current hardware address of a link
+func (n *Netlink) LinkMAC(link string) (string, error) {
+	l, err := netlink.LinkByName(link)
+	if err != nil {
+		return "", opError("link show "+link, err)
+	}
+	return l.Attrs().HardwareAddr.String(), nil
+}
+
+// SetLinkMAC sets the hardware address of a link
+func (n *Netlink) SetLinkMAC(link, mac string) error {
+	op := fmt.Sprintf("link set %s address %s", link, mac)
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return opError(op, err)
+	}
+	l, err := netlink.LinkByName(link)
+	if err != nil {
+		return opError(op, err)
+	}
+	if l.Attrs().HardwareAddr.String() == hw.String() {
+		return nil
+	}
+	return opError(op, netlink.LinkSetHardwareAddr(l, hw))
+}
+
+// SetLinkAlias sets the description of a link
+func (n *Netlink) SetLinkAlias(link, alias string) error {
+	op := fmt.Sprintf("link set %s alias %s", link, alias)
+	l, err := netlink.LinkByName(link)
+	if err != nil {
+		return opError(op, err)
+	}
+	if l.Attrs().Alias == alias {
+		return nil
+	}
+	return opError(op, netlink.LinkSetAlias(l, alias))
+}
+
+// LinkMTU returns the current MTU of a link
+func (n *Netlink) LinkMTU(link string) (int, error) {
+	l, err := netlink.LinkByName(link)
+	if err != nil {
+		return 0, opError("link show "+link, err)
+	}
+	return l.Attrs().MTU, nil
+}
+
+// SetLinkMTU sets the MTU of a link
+func (n *Netlink) SetLinkMTU(link string, mtu int) error {
+	op := fmt.Sprintf("link set %s mtu %d", link, mtu)
+	l, err := netlink.LinkByName(link)
+	if err != nil {
+		return opError(op, err)
+	}
+	if l.Attrs().MTU == mtu {
+		return nil
+	}
+	return opError(op, netlink.LinkSetMTU(l, mtu))
+}
+
+// SetLinkUp sets a link administratively up or down
+func (n *Netlink) SetLinkUp(link string, up bool) error {
+	op := fmt.Sprintf("link set %s %s", link, UpDown(up))
+	l, err := netlink.LinkByName(link)
+	if err != nil {
+		return opError(op, err)
+	}
+	if up {
+		return opError(op, netlink.LinkSetUp(l))
+	}
+	return opError(op, netlink.LinkSetDown(l))
+}
+
+// AddAddress adds an address to a link, doing nothing if it is already present
+func (n *Netlink) AddAddress(link, address string) error {
+	op := fmt.Sprintf("address add %s dev %s", address, link)
+	l, addr, err := linkAddr(link, address)
+	if err != nil {
+		return opError(op, err)
+	}
+	return opError(op, netlink.AddrReplace(l, addr))
+}
+
+// DeleteAddress removes an address from a link, doing nothing if it is not present
+func (n *Netlink) DeleteAddress(link, address string) error {
+	op := fmt.Sprintf("address del %s dev %s", address, link)
+	l, addr, err := linkAddr(link, address)
+	if err != nil {
+		return opError(op, err)
+	}
+	addrs, err := netlink.AddrList(l, netlink.FAMILY_ALL)
+	if err != nil {
+		return opError(op, err)
+	}
+	for _, a := range addrs {
+		if a.Equal(*addr) {
+			return opError(op, netlink.AddrDel(l, addr))
+		}
+	}
+	return nil
+}
+
+// Links returns the names of the links in the current network namespace
+func (n *Netlink) Links() ([]string, error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, opError("list links", err)
+	}
+	names := make([]string, 0, len(links))
+	for _, l := range links {
+		names = append(names, l.Attrs().Name)
+	}
+	return names, nil
+}
+
+// linkAddr looks up a link and parses an address in CIDR notation.
+// An address without prefix length is treated as a host address.
+func linkAddr(link, address string) (netlink.Link, *netlink.Addr, error) {
+	if !strings.Contains(address, "/") {
+		if ip := net.ParseIP(address); ip != nil && ip.To4() == nil {
+			address += "/128"
+		} else {
+			address += "/32"
+		}
+	}
+	addr, err := netlink.ParseAddr(address)
+	if err != nil {
+		return nil, nil, err
+	}
+	l, err := netlink.LinkByName(link)
+	if err != nil {
+		return nil, nil, err
+	}
+	return l, addr, nil
+}
+
+// defaultDst returns the default destination for the address family of ip
+func defaultDst(ip net.IP) *net.IPNet {
+	if ip.To4() != nil {
+		return &net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)}
+	}
+	return &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}
+}
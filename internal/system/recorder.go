@@ -0,0 +1,139 @@
+package system
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Recorder is a Backend that records operations instead of performing them
+type Recorder struct {
+	// Ops lists the recorded operations in the order they were requested
+	Ops []string
+	// Errors makes the operations with the given description fail.
+	// Failed operations are recorded as well.
+	Errors map[string]error
+	// LinkNames are the network interfaces reported by Links
+	LinkNames []string
+	// LinkMACs are the hardware addresses reported by LinkMAC
+	LinkMACs map[string]string
+	// LinkMTUs are the MTUs reported by LinkMTU; other links report 1500
+	LinkMTUs map[string]int
+}
+
+// NewRecorder creates an empty Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{Errors: make(map[string]error)}
+}
+
+// WriteResolvConf records writing the resolver configuration
+func (r *Recorder) WriteResolvConf(servers []string) error {
+	return r.record("write resolv.conf", servers...)
+}
+
+// RestartService records restarting a service
+func (r *Recorder) RestartService(name string) error {
+	return r.record("restart", name)
+}
+
+// ReplaceDefaultRoute records replacing the default route
+func (r *Recorder) ReplaceDefaultRoute(gateway string) error {
+	return r.record("route replace default via", gateway)
+}
+
+// DeleteDefaultRoute records removing the default route via a gateway
+func (r *Recorder) DeleteDefaultRoute(gateway string) error {
+	return r.record("route del default via", gateway)
+}
+
+// ReplaceRoute records adding or replacing a route
+func (r *Recorder) ReplaceRoute(route Route) error {
+	return r.record("route replace", route.String())
+}
+
+// DeleteRoute records removing a route
+func (r *Recorder) DeleteRoute(route Route) error {
+	return r.record("route del", route.String())
+}
+
+// LinkMAC returns the entry of LinkMACs without recording an operation
+func (r *Recorder) LinkMAC(link string) (string, error) {
+	return r.LinkMACs[link], nil
+}
+
+// SetLinkMAC records setting the hardware address of a link
+func (r *Recorder) SetLinkMAC(link, mac string) error {
+	return r.record("link set", link, "address", mac)
+}
+
+// AddAddress records adding an address to a link
+func (r *Recorder) AddAddress(link, address string) error {
+	return r.record("address add", address, "dev", link)
+}
+
+// DeleteAddress records removing an address from a link
+func (r *Recorder) DeleteAddress(link, address string) error {
+	return r.record("address del", address, "dev", link)
+}
+
+// SetLinkAlias records setting the description of a link
+func (r *Recorder) SetLinkAlias(link, alias string) error {
+	return r.record("link set", link, "alias", alias)
+}
+
+// LinkMTU returns the entry of LinkMTUs without recording an operation
+func (r *Recorder) LinkMTU(link string) (int, error) {
+	if mtu, ok := r.LinkMTUs[link]; ok {
+		return mtu, nil
+	}
+	return 1500, nil
+}
+
+// SetLinkMTU records setting the MTU of a link
+func (r *Recorder) SetLinkMTU(link string, mtu int) error {
+	return r.record("link set", link, "mtu", strconv.Itoa(mtu))
+}
+
+// SetLinkUp records setting a link up or down
+func (r *Recorder) SetLinkUp(link string, up bool) error {
+	return r.record("link set", link, UpDown(up))
+}
+
+// SetLinkMode records setting the speed and duplex of a link
+func (r *Recorder) SetLinkMode(link, speed, duplex string) error {
+	if speed == "auto" {
+		return r.record("ethtool", link, "autoneg on")
+	}
+	return r.record("ethtool", link, "speed", speed, "duplex", duplex, "autoneg off")
+}
+
+// SetLinkOffload records switching an offload feature of a link
+func (r *Recorder) SetLinkOffload(link, feature string, enabled bool) error {
+	return r.record("ethtool", link, "offload", feature, OnOff(enabled))
+}
+
+// StartDHCP records starting a DHCP or DHCPv6 client on a link
+func (r *Recorder) StartDHCP(link string, ipv6 bool) error {
+	return r.record(dhcpProtocol(ipv6)+" start", link)
+}
+
+// StopDHCP records stopping the DHCP or DHCPv6 client of a link
+func (r *Recorder) StopDHCP(link string, ipv6 bool) error {
+	return r.record(dhcpProtocol(ipv6)+" stop", link)
+}
+
+// SetIPv6Autoconf records switching IPv6 autoconfiguration on a link
+func (r *Recorder) SetIPv6Autoconf(link string, enabled bool) error {
+	return r.record("ipv6 autoconf", OnOff(enabled), link)
+}
+
+// Links returns LinkNames without recording an operation
+func (r *Recorder) Links() ([]string, error) {
+	return r.LinkNames, nil
+}
+
+// record appends an operation to Ops and returns the error configured for it
+func (r *Recorder) record(op string, args ...string) error {
+	op = strings.TrimSpace(op + " " + strings.Join(args, " "))
+	r.Ops = append(r.Ops, op)
+	return opError(op, r.Errors[op])
+}
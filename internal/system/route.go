@@ -0,0 +1,73 @@
+package system
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Route is a kernel route of the main routing table
+type Route struct {
+	// Prefix is the destination in CIDR notation
+	Prefix string
+	// Metric is the route priority; lower is preferred
+	Metric int
+	// Blackhole discards matching traffic instead of forwarding it
+	Blackhole bool
+	// NextHops are the forwarding targets; several form an ECMP route
+	NextHops []NextHop
+}
+
+// NextHop is a forwarding target of a route
+type NextHop struct {
+	// Gateway is the next-hop address, optionally with a zone naming the
+	// interface of an IPv6 link-local gateway; empty for interface routes
+	Gateway string
+	// Link is the outgoing interface; empty to let the kernel choose
+	Link string
+}
+
+// Args returns the route in the argument syntax of ip route
+func (r Route) Args() []string {
+	var args []string
+	if r.Blackhole {
+		args = append(args, "blackhole")
+	}
+	args = append(args, r.Prefix)
+	if len(r.NextHops) == 1 {
+		args = append(args, r.NextHops[0].args()...)
+	}
+	args = append(args, "metric", strconv.Itoa(r.Metric))
+	if len(r.NextHops) > 1 {
+		for _, nh := range r.NextHops {
+			args = append(append(args, "nexthop"), nh.args()...)
+		}
+	}
+	return args
+}
+
+// String returns the route as written by ip route
+func (r Route) String() string {
+	return strings.Join(r.Args(), " ")
+}
+
+// args returns the next-hop in the argument syntax of ip route
+func (nh NextHop) args() []string {
+	var args []string
+	gw, zone := splitZone(nh.Gateway)
+	if gw != "" {
+		args = append(args, "via", gw)
+	}
+	if link := nh.link(zone); link != "" {
+		args = append(args, "dev", link)
+	}
+	return args
+}
+
+// link returns the outgoing interface of the next-hop, taken from the zone
+// of the gateway if no interface is given
+func (nh NextHop) link(zone string) string {
+	if nh.Link != "" {
+		return nh.Link
+	}
+	return zone
+}
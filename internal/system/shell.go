@@ -0,0 +1,178 @@
+package system
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Shell is a Backend that applies configuration with sudo, systemctl and ip
+type Shell struct {
+	// ResolvConfPath is the resolver configuration file to write
+	ResolvConfPath string
+}
+
+// NewShell creates a Shell backend. The resolver configuration path can be
+// overridden with the RESOLV_CONF environment variable.
+func NewShell() *Shell {
+	path := os.Getenv("RESOLV_CONF")
+	if path == "" {
+		path = DefaultResolvConfPath
+	}
+	return &Shell{ResolvConfPath: path}
+}
+
+// WriteResolvConf writes the DNS servers to the resolver configuration file
+func (s *Shell) WriteResolvConf(servers []string) error {
+	return opError("write "+s.ResolvConfPath, os.WriteFile(s.ResolvConfPath, []byte(ResolvConf(servers)), 0644))
+}
+
+// RestartService restarts a systemd service
+func (s *Shell) RestartService(name string) error {
+	return s.run("sudo", "systemctl", "restart", name)
+}
+
+// ReplaceDefaultRoute sets the default route via the given gateway
+func (s *Shell) ReplaceDefaultRoute(gateway string) error {
+	addr, zone := splitZone(gateway)
+	if zone != "" {
+		return s.run("sudo", "ip", "route", "replace", "default", "via", addr, "dev", zone)
+	}
+	return s.run("sudo", "ip", "route", "replace", "default", "via", addr)
+}
+
+// DeleteDefaultRoute removes the default route via the given gateway from the
+// main table, doing nothing if it is not present
+func (s *Shell) DeleteDefaultRoute(gateway string) error {
+	args := []string{"ip", "route", "del", "default", "via"}
+	addr, zone := splitZone(gateway)
+	args = append(args, addr)
+	if zone != "" {
+		args = append(args, "dev", zone)
+	}
+	err := s.run("sudo", append(args, "table", "main")...)
+	if err != nil && strings.Contains(err.Error(), "No such process") {
+		return nil
+	}
+	return err
+}
+
+// ReplaceRoute adds or replaces a route
+func (s *Shell) ReplaceRoute(r Route) error {
+	return s.run("sudo", append([]string{"ip", "route", "replace"}, r.Args()...)...)
+}
+
+// DeleteRoute removes a route, doing nothing if it is not present
+func (s *Shell) DeleteRoute(r Route) error {
+	err := s.run("sudo", append([]string{"ip", "route", "del"}, r.Args()...)...)
+	if err != nil && strings.Contains(err.Error(), "No such process") {
+		return nil
+	}
+	return err
+}
+
+// LinkMAC returns the current hardware address of a link
+func (s *Shell) LinkMAC(link string) (string, error) {
+	iface, err := net.InterfaceByName(link)
+	if err != nil {
+		return "", opError("link show "+link, err)
+	}
+	return iface.HardwareAddr.String(), nil
+}
+
+// SetLinkMAC sets the hardware address of a link
+func (s *Shell) SetLinkMAC(link, mac string) error {
+	return s.run("sudo", "ip", "link", "set", "dev", link, "address", mac)
+}
+
+// AddAddress adds an address to a link
+func (s *Shell) AddAddress(link, address string) error {
+	return s.run("sudo", "ip", "address", "replace", address, "dev", link)
+}
+
+// DeleteAddress removes an address from a link
+func (s *Shell) DeleteAddress(link, address string) error {
+	return s.run("sudo", "ip", "address", "del", address, "dev", link)
+}
+
+// SetLinkAlias sets the description of a link
+func (s *Shell) SetLinkAlias(link, alias string) error {
+	return s.run("sudo", "ip", "link", "set", "dev", link, "alias", alias)
+}
+
+// LinkMTU returns the current MTU of a link
+func (s *Shell) LinkMTU(link string) (int, error) {
+	iface, err := net.InterfaceByName(link)
+	if err != nil {
+		return 0, opError("link show "+link, err)
+	}
+	return iface.MTU, nil
+}
+
+// SetLinkMTU sets the MTU of a link
+func (s *Shell) SetLinkMTU(link string, mtu int) error {
+	return s.run("sudo", "ip", "link", "set", "dev", link, "mtu", strconv.Itoa(mtu))
+}
+
+// SetLinkUp sets a link administratively up or down
+func (s *Shell) SetLinkUp(link string, up bool) error {
+	return s.run("sudo", "ip", "link", "set", "dev", link, UpDown(up))
+}
+
+// SetLinkMode sets the speed and duplex of a link with ethtool
+func (s *Shell) SetLinkMode(link, speed, duplex string) error {
+	if speed == "auto" {
+		return s.run("sudo", "ethtool", "-s", link, "autoneg", "on")
+	}
+	return s.run("sudo", "ethtool", "-s", link, "speed", speed, "duplex", duplex, "autoneg", "off")
+}
+
+// SetLinkOffload switches an offload feature of a link with ethtool
+func (s *Shell) SetLinkOffload(link, feature string, enabled bool) error {
+	return s.run("sudo", "ethtool", "-K", link, feature, OnOff(enabled))
+}
+
+// StartDHCP starts dhclient on a link
+func (s *Shell) StartDHCP(link string, ipv6 bool) error {
+	if ipv6 {
+		return s.run("sudo", "dhclient", "-6", link)
+	}
+	return s.run("sudo", "dhclient", link)
+}
+
+// StopDHCP releases the lease of dhclient on a link and stops it
+func (s *Shell) StopDHCP(link string, ipv6 bool) error {
+	if ipv6 {
+		return s.run("sudo", "dhclient", "-6", "-r", link)
+	}
+	return s.run("sudo", "dhclient", "-r", link)
+}
+
+// SetIPv6Autoconf switches IPv6 autoconfiguration on a link with sysctl. The
+// key is separated by slashes, so that the dot in a VLAN name such as
+// eth0.100 is not taken as a separator.
+func (s *Shell) SetIPv6Autoconf(link string, enabled bool) error {
+	value := "0"
+	if enabled {
+		value = "1"
+	}
+	return s.run("sudo", "sysctl", "-w", "net/ipv6/conf/"+link+"/autoconf="+value)
+}
+
+// Links returns the names of the network interfaces present on the system
+func (s *Shell) Links() ([]string, error) {
+	return interfaceNames()
+}
+
+// run executes a command and reports its output on failure
+func (s *Shell) run(name string, args ...string) error {
+	c := exec.Command(name, args...)
+	out, err := c.CombinedOutput()
+	if err != nil && len(out) > 0 {
+		err = fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return opError(c.String(), err)
+}
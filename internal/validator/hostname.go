@@ -0,0 +1,22 @@
+package validator
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var hostnameLabelRegex = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+
+// ValidateHostname checks if the given string is a valid host name (RFC 1123)
+func ValidateHostname(name string) error {
+	if len(name) == 0 || len(name) > 253 {
+		return errors.New("invalid hostname length (expected 1-253 characters)")
+	}
+	for _, label := range strings.Split(name, ".") {
+		if !hostnameLabelRegex.MatchString(label) {
+			return errors.New("invalid hostname format (expected letters, digits and hyphens)")
+		}
+	}
+	return nil
+}
@@ -2,37 +2,97 @@ package validator
 
 import (
 	"fmt"
-	"net"
+	"net/netip"
 	"strings"
 )
 
-// ValidateIPAddress validates an IP address with optional CIDR notation
-func ValidateIPAddress(ip string) error {
-	// If CIDR notation is used, separate IP address and network mask parts
-	if strings.Contains(ip, "/") {
-		ip, cidr, err := net.ParseCIDR(ip)
-		if err != nil {
-			return fmt.Errorf("invalid CIDR notation: %v", err)
-		}
-		if ip == nil {
-			return fmt.Errorf("invalid IP address in CIDR notation")
-		}
-		if cidr == nil {
-			return fmt.Errorf("invalid network mask in CIDR notation")
-		}
-		return nil
+// parseAddr parses an IPv4 or IPv6 address without prefix length. A zone is
+// only accepted on IPv6 link-local addresses, and IPv4-mapped IPv6 addresses
+// are rejected so that every IPv4 address has a single spelling.
+func parseAddr(s string) (netip.Addr, error) {
+	if strings.Contains(s, "/") {
+		return netip.Addr{}, fmt.Errorf("%s has a prefix length, expected an address without one", s)
 	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("%s is not an IPv4 or IPv6 address", s)
+	}
+	if addr.Is4In6() {
+		return netip.Addr{}, fmt.Errorf("%s is an IPv4-mapped IPv6 address, use %s instead", s, addr.Unmap())
+	}
+	if addr.Zone() != "" && !addr.IsLinkLocalUnicast() {
+		return netip.Addr{}, fmt.Errorf("%s has a zone, which is only valid on IPv6 link-local addresses", s)
+	}
+	return addr, nil
+}
+
+// parsePrefix parses an IPv4 or IPv6 address with prefix length
+func parsePrefix(s string) (netip.Prefix, error) {
+	if !strings.Contains(s, "/") {
+		return netip.Prefix{}, fmt.Errorf("%s has no prefix length, expected e.g. %s/24 or %s/64", s, s, s)
+	}
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("%s is not an IPv4 or IPv6 address with prefix length", s)
+	}
+	if prefix.Addr().Is4In6() {
+		return netip.Prefix{}, fmt.Errorf("%s is an IPv4-mapped IPv6 address", s)
+	}
+	return prefix, nil
+}
 
-	// For regular IP addresses
-	parsedIP := net.ParseIP(ip)
-	if parsedIP == nil {
-		return fmt.Errorf("invalid IP address format")
+// ValidateHostAddress validates a unicast IPv4 or IPv6 address without prefix
+// length, e.g. a DNS server
+func ValidateHostAddress(s string) error {
+	addr, err := parseAddr(s)
+	if err != nil {
+		return err
+	}
+	switch {
+	case addr.IsUnspecified():
+		return fmt.Errorf("%s is the unspecified address", s)
+	case addr.IsMulticast():
+		return fmt.Errorf("%s is a multicast address", s)
+	case addr == netip.AddrFrom4([4]byte{255, 255, 255, 255}):
+		return fmt.Errorf("%s is the broadcast address", s)
 	}
 	return nil
 }
 
-// ValidateDNSAddress validates a DNS server address
-func ValidateDNSAddress(dns string) error {
-	// DNS server address must be an IPv4 or IPv6 address
-	return ValidateIPAddress(dns)
+// ValidateAddressWithPrefix validates an interface address with prefix
+// length, e.g. 192.168.1.10/24 or 2001:db8::1/64
+func ValidateAddressWithPrefix(s string) error {
+	prefix, err := parsePrefix(s)
+	if err != nil {
+		return err
+	}
+	if err := ValidateHostAddress(prefix.Addr().String()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ValidatePrefix validates a network prefix without host bits, e.g. 10.0.0.0/8
+func ValidatePrefix(s string) error {
+	prefix, err := parsePrefix(s)
+	if err != nil {
+		return err
+	}
+	if prefix != prefix.Masked() {
+		return fmt.Errorf("%s has host bits set, expected %s", s, prefix.Masked())
+	}
+	return nil
+}
+
+// ValidateGateway validates a next-hop address. IPv6 link-local gateways may
+// name their interface as zone, e.g. fe80::1%eth0.
+func ValidateGateway(s string) error {
+	if err := ValidateHostAddress(s); err != nil {
+		return err
+	}
+	addr, _ := parseAddr(s)
+	if addr.IsLoopback() {
+		return fmt.Errorf("%s is a loopback address", s)
+	}
+	return nil
 }
@@ -0,0 +1,74 @@
+package validator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MTU limits of an interface
+const (
+	MinMTU = 68
+	MaxMTU = 16000
+)
+
+// MaxDescriptionLength is the longest interface description the kernel stores
+const MaxDescriptionLength = 255
+
+// LinkSpeeds are the values accepted for the ethernet link speed, in Mbit/s
+var LinkSpeeds = []string{"auto", "10", "100", "1000", "2500", "5000", "10000", "25000", "40000", "50000", "100000"}
+
+// Duplexes are the values accepted for the ethernet duplex mode
+var Duplexes = []string{"auto", "half", "full"}
+
+// OffloadFeatures are the ethtool offload features that can be enabled
+var OffloadFeatures = []string{"gro", "gso", "lro", "sg", "tso"}
+
+// ValidateMTU validates an interface MTU
+func ValidateMTU(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("%s is not a number", s)
+	}
+	if n < MinMTU || n > MaxMTU {
+		return fmt.Errorf("%d is out of range (expected %d-%d)", n, MinMTU, MaxMTU)
+	}
+	return nil
+}
+
+// ValidateDescription validates an interface description
+func ValidateDescription(s string) error {
+	if len(s) > MaxDescriptionLength {
+		return fmt.Errorf("description is %d bytes long (expected at most %d)", len(s), MaxDescriptionLength)
+	}
+	if strings.ContainsFunc(s, unicode.IsControl) {
+		return fmt.Errorf("description contains control characters")
+	}
+	return nil
+}
+
+// ValidateSpeed validates an ethernet link speed
+func ValidateSpeed(s string) error {
+	return oneOf(s, "link speed", LinkSpeeds)
+}
+
+// ValidateDuplex validates an ethernet duplex mode
+func ValidateDuplex(s string) error {
+	return oneOf(s, "duplex mode", Duplexes)
+}
+
+// ValidateOffload validates an offload feature name
+func ValidateOffload(s string) error {
+	return oneOf(s, "offload feature", OffloadFeatures)
+}
+
+// oneOf returns an error if s is not one of the allowed values
+func oneOf(s, what string, allowed []string) error {
+	for _, v := range allowed {
+		if s == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not a valid %s (expected one of %s)", s, what, strings.Join(allowed, ", "))
+}
@@ -0,0 +1,24 @@
+package validator
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Administrative distance limits of a static route
+const (
+	MinDistance = 1
+	MaxDistance = 255
+)
+
+// ValidateDistance validates the administrative distance of a static route
+func ValidateDistance(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("%s is not a number", s)
+	}
+	if n < MinDistance || n > MaxDistance {
+		return fmt.Errorf("%d is out of range (expected %d-%d)", n, MinDistance, MaxDistance)
+	}
+	return nil
+}